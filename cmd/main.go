@@ -13,9 +13,14 @@ package main
 import (
 	"context"
 	"encoding/gob"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/alexedwards/scs/pgxstore"
@@ -26,7 +31,10 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 	"github.com/lohanguedes/gobid/internal/api"
+	"github.com/lohanguedes/gobid/internal/mail"
+	"github.com/lohanguedes/gobid/internal/oidc"
 	"github.com/lohanguedes/gobid/internal/services"
+	"github.com/lohanguedes/gobid/internal/store/blobstore"
 )
 
 func init() {
@@ -54,6 +62,15 @@ func main() {
 		panic(err)
 	}
 
+	mediaDir := os.Getenv("GOBID_MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "./media"
+	}
+	blobs, err := blobstore.NewLocalStore(mediaDir, "/media")
+	if err != nil {
+		panic(err)
+	}
+
 	// Configuration
 	s := scs.New()
 	s.Store = pgxstore.New(pool)
@@ -61,12 +78,72 @@ func main() {
 	s.Cookie.HttpOnly = true
 	s.Cookie.SameSite = http.SameSiteLaxMode
 
+	mailer, err := newMailer()
+	if err != nil {
+		panic(err)
+	}
+	mailTemplates, err := mail.NewTemplates()
+	if err != nil {
+		panic(err)
+	}
+
+	// The page of the frontend the password reset emails link to.
+	passwordResetURL := os.Getenv("GOBID_PASSWORD_RESET_URL")
+	if passwordResetURL == "" {
+		passwordResetURL = "http://localhost:3000/reset-password"
+	}
+	// And the one the email verification emails link to.
+	verifyEmailURL := os.Getenv("GOBID_VERIFY_EMAIL_URL")
+	if verifyEmailURL == "" {
+		verifyEmailURL = "http://localhost:3000/verify-email"
+	}
+
+	oidcProviders, err := newOIDCProviders()
+	if err != nil {
+		panic(err)
+	}
+
+	// The notifications are written to the outbox by the services, then kept
+	// in the inbox of the users and pushed to their open streams, some of
+	// them are emailed too.
+	notificationHub := services.NewNotificationHub()
+	notificationService := services.NewNotificationService(pool, notificationHub)
+	emailNotifier := services.NewEmailNotifier(pool, mailer, mailTemplates)
+	// Only set GOBID_WEBHOOKS_ALLOW_PRIVATE in development, to receive the
+	// webhooks locally. Never in .env, it would turn the SSRF guard off everywhere.
+	allowPrivateWebhooks := os.Getenv("GOBID_WEBHOOKS_ALLOW_PRIVATE") == "true"
+	if allowPrivateWebhooks {
+		fmt.Println("GOBID_WEBHOOKS_ALLOW_PRIVATE is set, the webhooks may call private addresses")
+	}
+	webhookService := services.NewWebhookService(pool, allowPrivateWebhooks)
+	outboxService := services.NewOutboxService(pool, map[string]services.OutboxConsumer{
+		services.OutboxConsumerInbox:   services.NotificationConsumer{Notifier: &notificationService},
+		services.OutboxConsumerEmail:   services.NotificationConsumer{Notifier: &emailNotifier},
+		services.OutboxConsumerWebhook: &webhookService,
+	})
+
 	api := api.Api{
-		Router:         chi.NewMux(),
-		Session:        s,
-		UserService:    services.NewUserService(pool),
-		ProductService: services.NewProductService(pool),
-		BidsService:    services.NewBidsService(pool),
+		Router:                   chi.NewMux(),
+		Session:                  s,
+		UserService:              services.NewUserService(pool),
+		ProductService:           services.NewProductService(pool),
+		BidsService:              services.NewBidsService(pool),
+		AuctionMessagesService:   services.NewAuctionMessagesService(pool),
+		ProductImagesService:     services.NewProductImagesService(pool, blobs),
+		CategoryService:          services.NewCategoryService(pool),
+		WatchlistService:         services.NewWatchlistService(pool),
+		SavedSearchService:       services.NewSavedSearchService(pool),
+		NotificationService:      notificationService,
+		OutboxService:            outboxService,
+		WebhookService:           webhookService,
+		RatingService:            services.NewRatingService(pool),
+		PasswordResetService:     services.NewPasswordResetService(pool, mailer, mailTemplates, passwordResetURL),
+		EmailVerificationService: services.NewEmailVerificationService(pool, mailer, mailTemplates, verifyEmailURL),
+		TwoFactorService:         services.NewTwoFactorService(pool, "gobid"),
+		SessionService:           services.NewSessionService(pool),
+		APITokenService:          services.NewAPITokenService(pool),
+		OIDCService:              services.NewOIDCService(pool, oidcProviders),
+		Media:                    blobs.Handler(),
 		Upgrader: websocket.Upgrader{
 			// For tests and development only, otherwise make a actual function here...
 			CheckOrigin: func(r *http.Request) bool { return true },
@@ -78,8 +155,114 @@ func main() {
 
 	api.BindRoutes()
 
-	fmt.Println("Starting server on port :3080")
-	if err := http.ListenAndServe("localhost:3080", api.Router); err != nil {
-		panic(err)
+	schedulerCtx, stopScheduler := context.WithCancel(ctx)
+	defer stopScheduler()
+	scheduler := services.AuctionScheduler{
+		ProductService:     &api.ProductService,
+		SavedSearchService: &api.SavedSearchService,
+		Interval:           30 * time.Second,
+	}
+	go scheduler.Run(schedulerCtx)
+	go api.OutboxService.Run(schedulerCtx)
+
+	srv := &http.Server{
+		Addr:    "localhost:3080",
+		Handler: api.Router,
+	}
+
+	go func() {
+		fmt.Println("Starting server on port :3080")
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	fmt.Println("Shutting down server")
+	stopScheduler()
+	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// Hijacked websocket conns are not tracked by the http.Server, so the rooms say goodbye themselves.
+	if err := api.AuctionLobby.Shutdown(shutdownCtx); err != nil {
+		fmt.Printf("failed to close auction rooms: %v\n", err)
 	}
+	// The open notification streams would keep the server waiting until the timeout.
+	notificationHub.Close()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		fmt.Printf("failed to shutdown server: %v\n", err)
+	}
+}
+
+// newMailer sends the emails through GOBID_SMTP_HOST when it is set, writes
+// them to GOBID_MAIL_DIR when that is set instead and drops them otherwise.
+func newMailer() (mail.Mailer, error) {
+	from := os.Getenv("GOBID_MAIL_FROM")
+	if from == "" {
+		from = "gobid <no-reply@gobid.local>"
+	}
+
+	if host := os.Getenv("GOBID_SMTP_HOST"); host != "" {
+		port := os.Getenv("GOBID_SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return &mail.SMTPMailer{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("GOBID_SMTP_USERNAME"),
+			Password: os.Getenv("GOBID_SMTP_PASSWORD"),
+			From:     from,
+		}, nil
+	}
+
+	if dir := os.Getenv("GOBID_MAIL_DIR"); dir != "" {
+		return mail.NewFileMailer(dir, from)
+	}
+
+	return mail.NopMailer{}, nil
+}
+
+// newOIDCProviders reads the identity providers users may sign in with from the
+// JSON list in GOBID_OIDC_PROVIDERS_FILE, there are none when it is not set.
+// Each provider must accept GOBID_OIDC_CALLBACK_URL/<name>/callback as its
+// redirect uri.
+func newOIDCProviders() ([]*oidc.Provider, error) {
+	path := os.Getenv("GOBID_OIDC_PROVIDERS_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	callbackURL := os.Getenv("GOBID_OIDC_CALLBACK_URL")
+	if callbackURL == "" {
+		callbackURL = "http://localhost:3080/api/v1/users/oidc"
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var configs []oidc.Config
+	if err := json.Unmarshal(raw, &configs); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+
+	providers := make([]*oidc.Provider, 0, len(configs))
+	seen := make(map[string]bool, len(configs))
+	for _, config := range configs {
+		if err := config.Valid(); err != nil {
+			return nil, err
+		}
+		if seen[config.Name] {
+			return nil, fmt.Errorf("oidc: the provider %s is listed twice", config.Name)
+		}
+		seen[config.Name] = true
+
+		redirectURL := strings.TrimSuffix(callbackURL, "/") + "/" + config.Name + "/callback"
+		providers = append(providers, oidc.NewProvider(config, redirectURL, nil))
+	}
+
+	return providers, nil
 }
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"net/http"
+
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-chi/chi/v5"
 	"github.com/gorilla/websocket"
@@ -9,11 +11,28 @@ import (
 
 // This file is only used for documentind the api constraints. and injecting dependencies.
 type Api struct {
-	Router         *chi.Mux
-	Session        *scs.SessionManager
-	UserService    services.UserService
-	ProductService services.ProductService
-	BidsService    services.BidsService
-	Upgrader       websocket.Upgrader
-	AuctionLobby   services.AuctionLobby
+	Router                   *chi.Mux
+	Session                  *scs.SessionManager
+	UserService              services.UserService
+	ProductService           services.ProductService
+	BidsService              services.BidsService
+	AuctionMessagesService   services.AuctionMessagesService
+	ProductImagesService     services.ProductImagesService
+	CategoryService          services.CategoryService
+	WatchlistService         services.WatchlistService
+	SavedSearchService       services.SavedSearchService
+	NotificationService      services.NotificationService
+	OutboxService            services.OutboxService
+	WebhookService           services.WebhookService
+	RatingService            services.RatingService
+	PasswordResetService     services.PasswordResetService
+	EmailVerificationService services.EmailVerificationService
+	TwoFactorService         services.TwoFactorService
+	SessionService           services.SessionService
+	APITokenService          services.APITokenService
+	OIDCService              services.OIDCService
+	Upgrader                 websocket.Upgrader
+	AuctionLobby             services.AuctionLobby
+	// Serves the product images when they are kept on the local disk, nil otherwise.
+	Media http.Handler
 }
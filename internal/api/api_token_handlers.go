@@ -0,0 +1,106 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/gobid/internal/services"
+	"github.com/lohanguedes/gobid/internal/usecase/user"
+)
+
+// GET /users/me/api-tokens
+func (api *Api) handleListAPITokens(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	tokens, err := api.APITokenService.List(r.Context(), userID)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "failed to list api tokens",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"api_tokens": tokens,
+	})
+}
+
+// POST /users/me/api-tokens
+func (api *Api) handleCreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	data, problems, err := decodeValidJson[user.CreateAPITokenReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	token, err := api.APITokenService.Create(r.Context(), userID, data.Name, data.Scopes, data.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, services.ErrTooManyAPITokens) {
+			_ = encodeJson(w, r, http.StatusConflict, map[string]any{
+				"error": err.Error(),
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "failed to create api token",
+		})
+		return
+	}
+
+	// The token is only shown here.
+	_ = encodeJson(w, r, http.StatusCreated, map[string]any{
+		"api_token": token,
+	})
+}
+
+// DELETE /users/me/api-tokens/{id}
+func (api *Api) handleRevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	if err := api.APITokenService.Revoke(r.Context(), userID, id); err != nil {
+		if errors.Is(err, services.ErrAPITokenNotFound) {
+			_ = encodeJson(w, r, http.StatusNotFound, map[string]any{
+				"error": err.Error(),
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "failed to revoke api token",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message": "api token revoked",
+	})
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"errors"
+	"log/slog"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -36,7 +37,7 @@ func (api *Api) handleSubcribeUserToAuction(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Get the room info before
-	userId, ok := api.Session.Get(r.Context(), "authenticatedUserId").(uuid.UUID)
+	userId, ok := authenticatedUserID(r.Context())
 	if !ok {
 		encodeJson(w, r, http.StatusInternalServerError, map[string]any{
 			"message": "failed to authenticate your session",
@@ -44,10 +45,7 @@ func (api *Api) handleSubcribeUserToAuction(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	api.AuctionLobby.Lock()
-	room, ok := api.AuctionLobby.Rooms[productId]
-	api.AuctionLobby.Unlock()
-
+	room, ok := api.AuctionLobby.Room(productId)
 	if !ok {
 		encodeJson(w, r, http.StatusBadRequest, map[string]any{
 			"message": "The Auction has been endded or does not exist.",
@@ -65,7 +63,8 @@ func (api *Api) handleSubcribeUserToAuction(w http.ResponseWriter, r *http.Reque
 	}
 	client := services.NewClient(room, conn, userId)
 
-	go client.ReadEventLoop()
-	go client.WriteEventLoop()
-	room.Register <- client
+	// The room may have been closed while upgrading, Join already closed the conn with the right code.
+	if err := room.Join(client); err != nil {
+		slog.Info("Could not join auction room", "RoomId", productId, "error", err)
+	}
 }
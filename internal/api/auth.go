@@ -1,9 +1,19 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"slices"
+	"strings"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/csrf"
+	"github.com/lohanguedes/gobid/internal/authz"
+	"github.com/lohanguedes/gobid/internal/services"
 )
 
 func (api *Api) handleGetCSRFToken(w http.ResponseWriter, r *http.Request) {
@@ -11,13 +21,192 @@ func (api *Api) handleGetCSRFToken(w http.ResponseWriter, r *http.Request) {
 	encodeJson(w, r, http.StatusOK, map[string]string{"csrf_token": token})
 }
 
+type contextKey int
+
+const (
+	userIDKey contextKey = iota
+	tokenScopeKey
+)
+
+// authenticatedUserID is the user AuthMiddleware logged the request in as,
+// with the session or with an api token.
+func authenticatedUserID(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := ctx.Value(userIDKey).(uuid.UUID)
+	return userID, ok
+}
+
+// AllowTokens lets AuthMiddleware accept the api tokens with the scope, it must
+// run before it. The routes without it only work with a session, so a leaked
+// token cannot change the password or create more tokens.
+func (api *Api) AllowTokens(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tokenScopeKey, scope)))
+		})
+	}
+}
+
 func (api *Api) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !api.Session.Exists(r.Context(), "authenticatedUserId") {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			api.authenticateToken(w, r, strings.TrimSpace(token), next)
+			return
+		}
+
+		userID, ok := api.Session.Get(r.Context(), "authenticatedUserId").(uuid.UUID)
+		if !ok {
+			_ = encodeJson(w, r, http.StatusUnauthorized, map[string]any{"message": "must be logged in"})
+			return
+		}
+
+		// Not worth failing the request for, the session list is only a bit stale.
+		err := api.SessionService.Touch(r.Context(), api.Session.Token(r.Context()), userID, clientIP(r), r.UserAgent())
+		if err != nil {
+			slog.Error("Failed to record the session activity", "user_id", userID, "error", err)
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userIDKey, userID)))
+	})
+}
+
+func (api *Api) authenticateToken(w http.ResponseWriter, r *http.Request, token string, next http.Handler) {
+	auth, err := api.APITokenService.Authenticate(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAPIToken) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			_ = encodeJson(w, r, http.StatusUnauthorized, map[string]any{"message": err.Error()})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	scope, ok := r.Context().Value(tokenScopeKey).(string)
+	if !ok {
+		_ = encodeJson(w, r, http.StatusForbidden, map[string]any{"message": "api tokens cannot be used here, log in instead"})
+		return
+	}
+	if !slices.Contains(auth.Scopes, scope) {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, scope))
+		_ = encodeJson(w, r, http.StatusForbidden, map[string]any{"message": "the api token needs the " + scope + " scope"})
+		return
+	}
+
+	next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userIDKey, auth.UserID)))
+}
+
+// RequirePermission must run after AuthMiddleware, it lets through only the
+// users whose role has the permission.
+func (api *Api) RequirePermission(perm authz.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := authenticatedUserID(r.Context())
+			if !ok {
+				_ = encodeJson(w, r, http.StatusUnauthorized, map[string]any{"message": "must be logged in"})
+				return
+			}
+
+			role, err := api.UserService.Role(r.Context(), userID)
+			if err != nil {
+				_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+					"error": "unexpected error try again later",
+				})
+				return
+			}
+			if !role.Can(perm) {
+				_ = encodeJson(w, r, http.StatusForbidden, map[string]any{"message": "your role does not allow this"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// VerifiedMiddleware must run after AuthMiddleware, it lets through only the
+// users that verified their email.
+func (api *Api) VerifiedMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := authenticatedUserID(r.Context())
+		if !ok {
 			_ = encodeJson(w, r, http.StatusUnauthorized, map[string]any{"message": "must be logged in"})
 			return
 		}
 
+		verified, err := api.UserService.IsEmailVerified(r.Context(), userID)
+		if err != nil {
+			_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+				"error": "unexpected error try again later",
+			})
+			return
+		}
+		if !verified {
+			_ = encodeJson(w, r, http.StatusForbidden, map[string]any{"message": "must verify your email first"})
+			return
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
+
+// startSession logs the request in as the user, under a new token so a token
+// set before the login cannot be used to take the session over.
+func (api *Api) startSession(r *http.Request, userID uuid.UUID) error {
+	if err := api.Session.RenewToken(r.Context()); err != nil {
+		return err
+	}
+	api.Session.Put(r.Context(), "authenticatedUserId", userID)
+
+	return api.SessionService.Touch(r.Context(), api.Session.Token(r.Context()), userID, clientIP(r), r.UserAgent())
+}
+
+// renewSession gives the session of the request a new token, it stays in the
+// session list of the user.
+func (api *Api) renewSession(r *http.Request) error {
+	token := api.Session.Token(r.Context())
+	if err := api.Session.RenewToken(r.Context()); err != nil {
+		return err
+	}
+
+	return api.SessionService.Rename(r.Context(), token, api.Session.Token(r.Context()))
+}
+
+// revokeSessions logs the user out of every session but the one with the keep
+// token, an empty keep logs them out everywhere.
+func (api *Api) revokeSessions(ctx context.Context, userID uuid.UUID, keep string) error {
+	tokens, err := api.SessionService.Tokens(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	revoked := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if keep != "" && token == keep {
+			continue
+		}
+		// The current session must go through scs, it would save it again otherwise.
+		if token == api.Session.Token(ctx) {
+			err = api.Session.Destroy(ctx)
+		} else {
+			err = api.Session.Store.Delete(token)
+		}
+		if err != nil {
+			return err
+		}
+		revoked = append(revoked, token)
+	}
+
+	return api.SessionService.Forget(ctx, revoked...)
+}
+
+// clientIP is the address the request came from. Behind a proxy it is the
+// address of the proxy, which must then set RemoteAddr from its headers.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/gobid/internal/services"
+	"github.com/lohanguedes/gobid/internal/usecase/category"
+)
+
+// encodeCategoryError answers the errors shared by the category admin endpoints.
+func encodeCategoryError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrCategoryNotFound):
+		_ = encodeJson(w, r, http.StatusNotFound, map[string]any{
+			"error": "category with given id not found",
+		})
+	case errors.Is(err, services.ErrDuplicateCategorySlug):
+		_ = encodeJson(w, r, http.StatusConflict, map[string]any{
+			"slug": err.Error(),
+		})
+	case errors.Is(err, services.ErrCategoryCycle):
+		_ = encodeJson(w, r, http.StatusUnprocessableEntity, map[string]any{
+			"parent_id": err.Error(),
+		})
+	case errors.Is(err, services.ErrCategoryInUse):
+		_ = encodeJson(w, r, http.StatusConflict, map[string]any{
+			"error": err.Error(),
+		})
+	default:
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+	}
+}
+
+// GET /categories
+func (api *Api) handleListCategories(w http.ResponseWriter, r *http.Request) {
+	tree, err := api.CategoryService.Tree(r.Context())
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "failed to list categories",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"categories": tree,
+	})
+}
+
+// GET /categories/{id}/attributes
+func (api *Api) handleGetCategoryAttributes(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	schema, err := api.CategoryService.Schema(r.Context(), id)
+	if err != nil {
+		encodeCategoryError(w, r, err)
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"attributes": schema,
+	})
+}
+
+// POST /admin/categories
+func (api *Api) handleCreateCategory(w http.ResponseWriter, r *http.Request) {
+	data, problems, err := decodeValidJson[category.CreateCategoryReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	created, err := api.CategoryService.CreateCategory(r.Context(), data.ParentID, data.Name, data.Slug, data.AttributeSchema)
+	if err != nil {
+		encodeCategoryError(w, r, err)
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusCreated, created)
+}
+
+// PUT /admin/categories/{id}
+func (api *Api) handleUpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	data, problems, err := decodeValidJson[category.UpdateCategoryReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	updated, err := api.CategoryService.UpdateCategory(r.Context(), id, data.ParentID, data.Name, data.Slug, data.AttributeSchema)
+	if err != nil {
+		encodeCategoryError(w, r, err)
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, updated)
+}
+
+// DELETE /admin/categories/{id}
+func (api *Api) handleDeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	if err := api.CategoryService.DeleteCategory(r.Context(), id); err != nil {
+		encodeCategoryError(w, r, err)
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message": "category deleted",
+	})
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/lohanguedes/gobid/internal/services"
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+// GET /admin/login-attempts?email=&ip=&outcome=&limit=
+func (api *Api) handleListLoginAttempts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := int64(50)
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil || parsed < 1 || parsed > 500 {
+			_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+				"limit": "must be between 1 and 500",
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	filter := services.LoginAttemptFilter{
+		Email:   query.Get("email"),
+		IP:      query.Get("ip"),
+		Outcome: query.Get("outcome"),
+	}
+	if filter.Outcome != "" && !validator.PermittedValue(filter.Outcome, services.LoginSucceeded, services.LoginFailed, services.LoginLocked) {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"outcome": "must be one of succeeded, failed or locked",
+		})
+		return
+	}
+
+	attempts, err := api.UserService.LoginAttempts(r.Context(), filter, int32(limit))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "failed to list the login attempts",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"attempts": attempts,
+	})
+}
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/gobid/internal/services"
+	"github.com/lohanguedes/gobid/internal/usecase/notification"
+)
+
+// Keeps proxies from closing an idle notification stream.
+const notificationStreamHeartbeat = 25 * time.Second
+
+// GET /notifications
+func (api *Api) handleListNotifications(w http.ResponseWriter, r *http.Request) {
+	data, problems := notification.NewListNotificationsReq(r.URL.Query())
+	if len(problems) == 0 {
+		problems = data.Valid(r.Context())
+	}
+	if len(problems) > 0 {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	page, err := api.NotificationService.List(r.Context(), userID, data.UnreadOnly, data.Cursor, data.Limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) {
+			_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+				"cursor": err.Error(),
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "failed to list notifications",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, page)
+}
+
+// POST /notifications/{id}/read
+func (api *Api) handleMarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	if err := api.NotificationService.MarkRead(r.Context(), userID, id); err != nil {
+		if errors.Is(err, services.ErrNotificationNotFound) {
+			_ = encodeJson(w, r, http.StatusNotFound, map[string]any{
+				"error": "notification with given id not found",
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message": "notification marked as read",
+	})
+}
+
+// POST /notifications/read-all
+func (api *Api) handleMarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	updated, err := api.NotificationService.MarkAllRead(r.Context(), userID)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message": "notifications marked as read",
+		"updated": updated,
+	})
+}
+
+// GET /notifications/stream
+//
+// Server-sent events: an unread_count event when the stream opens, then a
+// notification event for each new notification of the user.
+func (api *Api) handleNotificationStream(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	// Subscribe first so nothing is missed between the count and the stream.
+	notifications, unsubscribe := api.NotificationService.Subscribe(userID)
+	defer unsubscribe()
+
+	unread, err := api.NotificationService.UnreadCount(r.Context(), userID)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeEvent(w, "unread_count", map[string]any{"unread_count": unread}); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		slog.Error("Failed to flush the notification stream", "error", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(notificationStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case n, ok := <-notifications:
+			// The server is shutting down.
+			if !ok {
+				return
+			}
+			err = writeEvent(w, "notification", n)
+		case <-heartbeat.C:
+			_, err = io.WriteString(w, ": ping\n\n")
+		}
+		if err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w io.Writer, event string, data any) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, encoded)
+	return err
+}
+
+// GET /users/me/notification-preferences
+func (api *Api) handleGetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	prefs, err := api.NotificationService.EmailPreferences(r.Context(), userID)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"email": prefs,
+	})
+}
+
+// PATCH /users/me/notification-preferences
+func (api *Api) handleUpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	data, problems, err := decodeValidJson[notification.UpdatePreferencesReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	prefs, err := api.NotificationService.SetEmailPreferences(r.Context(), userID, data.Email)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"email": prefs,
+	})
+}
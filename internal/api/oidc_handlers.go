@@ -0,0 +1,165 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lohanguedes/gobid/internal/oidc"
+	"github.com/lohanguedes/gobid/internal/services"
+)
+
+// How long the user has to log in at the provider.
+const oidcLoginTTL = 10 * time.Minute
+
+func (api *Api) clearPendingOIDCLogin(r *http.Request) {
+	api.Session.Remove(r.Context(), "oidcProvider")
+	api.Session.Remove(r.Context(), "oidcState")
+	api.Session.Remove(r.Context(), "oidcNonce")
+	api.Session.Remove(r.Context(), "oidcVerifier")
+	api.Session.Remove(r.Context(), "oidcStartedAt")
+}
+
+// GET /users/oidc
+func (api *Api) handleListOIDCProviders(w http.ResponseWriter, r *http.Request) {
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"providers": api.OIDCService.Providers(),
+	})
+}
+
+// GET /users/oidc/{provider}/login sends the browser to the login page of the
+// provider, which sends it back to the callback below.
+func (api *Api) handleStartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	provider, err := api.OIDCService.Provider(chi.URLParam(r, "provider"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusNotFound, map[string]any{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var state, nonce, verifier string
+	for _, s := range []*string{&state, &nonce, &verifier} {
+		if *s, err = oidc.RandomString(); err != nil {
+			_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+				"error": "unexpected error, try again later.",
+			})
+			return
+		}
+	}
+
+	authURL, err := provider.AuthURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		slog.Error("Failed to reach the identity provider", "provider", provider.Name(), "error", err)
+		_ = encodeJson(w, r, http.StatusBadGateway, map[string]any{
+			"error": "the identity provider is unavailable, try again later",
+		})
+		return
+	}
+
+	api.Session.Put(r.Context(), "oidcProvider", provider.Name())
+	api.Session.Put(r.Context(), "oidcState", state)
+	api.Session.Put(r.Context(), "oidcNonce", nonce)
+	api.Session.Put(r.Context(), "oidcVerifier", verifier)
+	api.Session.Put(r.Context(), "oidcStartedAt", time.Now().Unix())
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// GET /users/oidc/{provider}/callback
+func (api *Api) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	name := api.Session.GetString(r.Context(), "oidcProvider")
+	state := api.Session.GetString(r.Context(), "oidcState")
+	nonce := api.Session.GetString(r.Context(), "oidcNonce")
+	verifier := api.Session.GetString(r.Context(), "oidcVerifier")
+	startedAt := time.Unix(api.Session.GetInt64(r.Context(), "oidcStartedAt"), 0)
+	// The state works once, a replayed callback starts over.
+	api.clearPendingOIDCLogin(r)
+
+	query := r.URL.Query()
+	if name == "" || name != chi.URLParam(r, "provider") || time.Since(startedAt) > oidcLoginTTL ||
+		subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "the login expired or was started in another browser, try again",
+		})
+		return
+	}
+	if reason := query.Get("error"); reason != "" {
+		_ = encodeJson(w, r, http.StatusUnauthorized, map[string]any{
+			"error":             "the identity provider refused the login",
+			"provider_error":    reason,
+			"error_description": query.Get("error_description"),
+		})
+		return
+	}
+
+	provider, err := api.OIDCService.Provider(name)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusNotFound, map[string]any{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	claims, err := provider.Exchange(r.Context(), query.Get("code"), verifier, nonce)
+	if err != nil {
+		slog.Error("Failed to complete the login at the identity provider", "provider", name, "error", err)
+		if errors.Is(err, oidc.ErrInvalidIDToken) {
+			_ = encodeJson(w, r, http.StatusUnauthorized, map[string]any{
+				"error": "the identity provider sent an invalid token",
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusBadGateway, map[string]any{
+			"error": "the identity provider is unavailable, try again later",
+		})
+		return
+	}
+
+	id, err := api.OIDCService.Login(r.Context(), name, claims, clientIP(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrOIDCEmailNotVerified):
+			_ = encodeJson(w, r, http.StatusForbidden, map[string]any{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrOIDCAccountNotVerified):
+			_ = encodeJson(w, r, http.StatusConflict, map[string]any{
+				"error": err.Error(),
+			})
+		default:
+			_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+				"error": "unexpected error, try again later.",
+			})
+		}
+		return
+	}
+
+	api.completeLogin(w, r, id)
+}
+
+// GET /users/me/identities
+func (api *Api) handleListIdentities(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	identities, err := api.OIDCService.Identities(r.Context(), userID)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "failed to list identities",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"identities": identities,
+	})
+}
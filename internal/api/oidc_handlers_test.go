@@ -0,0 +1,152 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/lohanguedes/gobid/internal/oidc"
+	"github.com/lohanguedes/gobid/internal/services"
+)
+
+// newOIDCTestServer serves the login and the callback of a provider that
+// only answers the discovery, the callbacks tested here stop before the
+// code is exchanged.
+func newOIDCTestServer(t *testing.T) (*httptest.Server, *http.Client) {
+	t.Helper()
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 "http://" + r.Host,
+			"authorization_endpoint": "http://" + r.Host + "/authorize",
+			"token_endpoint":         "http://" + r.Host + "/token",
+			"jwks_uri":               "http://" + r.Host + "/jwks",
+		})
+	}))
+	t.Cleanup(idp.Close)
+
+	var providers []*oidc.Provider
+	for _, name := range []string{"mock", "other"} {
+		providers = append(providers, oidc.NewProvider(oidc.Config{
+			Name:     name,
+			Issuer:   idp.URL,
+			ClientID: "gobid",
+		}, "http://localhost/api/v1/users/oidc/"+name+"/callback", idp.Client()))
+	}
+
+	api := Api{
+		Session:     scs.New(),
+		OIDCService: services.NewOIDCService(nil, providers),
+	}
+	r := chi.NewRouter()
+	r.Use(api.Session.LoadAndSave)
+	r.Get("/users/oidc/{provider}/login", api.handleStartOIDCLogin)
+	r.Get("/users/oidc/{provider}/callback", api.handleOIDCCallback)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return srv, client
+}
+
+// startOIDCLogin returns the state the provider would send back.
+func startOIDCLogin(t *testing.T, srv *httptest.Server, client *http.Client, provider string) string {
+	t.Helper()
+	res, err := client.Get(srv.URL + "/users/oidc/" + provider + "/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("got status %d, want a redirect to the provider", res.StatusCode)
+	}
+
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := location.Query().Get("state")
+	if state == "" {
+		t.Fatal("the redirect to the provider has no state")
+	}
+	return state
+}
+
+func callback(t *testing.T, srv *httptest.Server, client *http.Client, provider string, query url.Values) int {
+	t.Helper()
+	res, err := client.Get(srv.URL + "/users/oidc/" + provider + "/callback?" + query.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return res.StatusCode
+}
+
+func TestOIDCCallbackState(t *testing.T) {
+	// The provider refusing the login is the first answer past the state check.
+	refused := func(state string) url.Values {
+		return url.Values{"state": {state}, "error": {"access_denied"}}
+	}
+
+	t.Run("matching state", func(t *testing.T) {
+		srv, client := newOIDCTestServer(t)
+		state := startOIDCLogin(t, srv, client, "mock")
+		if got := callback(t, srv, client, "mock", refused(state)); got != http.StatusUnauthorized {
+			t.Fatalf("got status %d, want %d", got, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("state mismatch", func(t *testing.T) {
+		srv, client := newOIDCTestServer(t)
+		startOIDCLogin(t, srv, client, "mock")
+		if got := callback(t, srv, client, "mock", refused("forged")); got != http.StatusBadRequest {
+			t.Fatalf("got status %d, want %d", got, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("no state", func(t *testing.T) {
+		srv, client := newOIDCTestServer(t)
+		startOIDCLogin(t, srv, client, "mock")
+		if got := callback(t, srv, client, "mock", url.Values{"error": {"access_denied"}}); got != http.StatusBadRequest {
+			t.Fatalf("got status %d, want %d", got, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("no pending login", func(t *testing.T) {
+		srv, client := newOIDCTestServer(t)
+		if got := callback(t, srv, client, "mock", refused("")); got != http.StatusBadRequest {
+			t.Fatalf("got status %d, want %d", got, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("another provider", func(t *testing.T) {
+		srv, client := newOIDCTestServer(t)
+		state := startOIDCLogin(t, srv, client, "mock")
+		if got := callback(t, srv, client, "other", refused(state)); got != http.StatusBadRequest {
+			t.Fatalf("got status %d, want %d", got, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("replayed", func(t *testing.T) {
+		srv, client := newOIDCTestServer(t)
+		state := startOIDCLogin(t, srv, client, "mock")
+		callback(t, srv, client, "mock", refused("forged"))
+		// The failed attempt used up the state.
+		if got := callback(t, srv, client, "mock", refused(state)); got != http.StatusBadRequest {
+			t.Fatalf("got status %d, want %d", got, http.StatusBadRequest)
+		}
+	})
+}
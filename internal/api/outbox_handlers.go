@@ -0,0 +1,66 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/gobid/internal/services"
+)
+
+// GET /admin/outbox/dead
+func (api *Api) handleListDeadOutboxEvents(w http.ResponseWriter, r *http.Request) {
+	limit := int64(50)
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil || parsed < 1 || parsed > 500 {
+			_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+				"limit": "must be between 1 and 500",
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	events, err := api.OutboxService.DeadLetters(r.Context(), int32(limit))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "failed to list the dead-lettered events",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"events": events,
+	})
+}
+
+// POST /admin/outbox/{id}/retry
+func (api *Api) handleRetryOutboxEvent(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	if err := api.OutboxService.Requeue(r.Context(), id); err != nil {
+		if errors.Is(err, services.ErrOutboxEventNotFound) {
+			_ = encodeJson(w, r, http.StatusNotFound, map[string]any{
+				"error": "dead-lettered event with given id not found",
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message": "event queued for delivery",
+	})
+}
@@ -2,8 +2,10 @@ package api
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/lohanguedes/gobid/internal/services"
@@ -17,7 +19,7 @@ func (api *Api) handleCreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, ok := api.Session.Get(r.Context(), "authenticatedUserId").(uuid.UUID)
+	userID, ok := authenticatedUserID(r.Context())
 	if !ok {
 		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
 			"error": "unexpected error try again later",
@@ -28,41 +30,420 @@ func (api *Api) handleCreateProduct(w http.ResponseWriter, r *http.Request) {
 	id, err := api.ProductService.CreateProduct(
 		r.Context(),
 		userID,
+		data.CategoryID,
 		data.ProductName,
+		data.Description,
+		data.Attributes,
 		data.BasePrice,
 		pgtype.Timestamptz{Time: data.AuctionStart, Valid: true},
 		pgtype.Timestamptz{Time: data.AuctionEnd, Valid: true},
 	)
 	if err != nil {
+		var attrErr *services.AttributesError
+		if errors.As(err, &attrErr) {
+			_ = encodeJson(w, r, http.StatusUnprocessableEntity, attrErr.Problems)
+			return
+		}
+		if errors.Is(err, services.ErrCategoryNotFound) {
+			_ = encodeJson(w, r, http.StatusUnprocessableEntity, map[string]any{
+				"category_id": "category does not exist",
+			})
+			return
+		}
 		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
 			"error": "failed to create product auction",
 		})
+		return
 	}
 
 	// We're using context.Background() because if we use r.Context() the context will be
 	// cancelled whenever the request is finished, killing our go-routine before any user is able to join in it.
-	ctx, _ := context.WithDeadline(context.Background(), data.AuctionEnd)
-
-	newAuctionRoom := services.NewAuctionRoom(ctx, id, &api.ProductService, &api.BidsService)
-	go newAuctionRoom.Run()
-
-	api.AuctionLobby.Lock()
-	api.AuctionLobby.Rooms[id] = newAuctionRoom
-	api.AuctionLobby.Unlock()
+	newAuctionRoom := services.NewAuctionRoom(context.Background(), id, data.AuctionEnd, &api.ProductService, &api.BidsService, &api.AuctionMessagesService)
+	api.AuctionLobby.Open(newAuctionRoom)
 
 	_ = encodeJson(w, r, http.StatusCreated, map[string]any{
 		"product_id": id,
 	})
 }
 
-// GET
+// GET /list?status=&seller_id=&category_id=&attr.<name>=&min_price=&max_price=&sort=&cursor=&limit=
 func (api *Api) handleListProducts(w http.ResponseWriter, r *http.Request) {
-	encodeJson(w, r, http.StatusOK, map[string]any{
-		"data": "hey!",
-	})
+	data, problems := product.NewListProductsReq(r.URL.Query())
+	if len(problems) == 0 {
+		problems = data.Valid(r.Context())
+	}
+	if len(problems) > 0 {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	page, err := api.ProductService.ListProducts(
+		r.Context(),
+		services.ProductFilter{
+			Status:     data.Status,
+			SellerID:   data.SellerID,
+			MinPrice:   data.MinPrice,
+			MaxPrice:   data.MaxPrice,
+			CategoryID: data.CategoryID,
+			Attributes: data.Attributes,
+		},
+		data.Sort,
+		data.Cursor,
+		data.Limit,
+	)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) {
+			_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+				"cursor": err.Error(),
+			})
+			return
+		}
+		var attrErr *services.AttributesError
+		if errors.As(err, &attrErr) {
+			_ = encodeJson(w, r, http.StatusBadRequest, attrErr.Problems)
+			return
+		}
+		if errors.Is(err, services.ErrCategoryNotFound) {
+			_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+				"category_id": "category does not exist",
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "failed to list products",
+		})
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(page.Products))
+	for _, p := range page.Products {
+		ids = append(ids, p.ID)
+	}
+	images, err := api.ProductImagesService.ImagesByProduct(r.Context(), ids...)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "failed to list products",
+		})
+		return
+	}
+	for i := range page.Products {
+		page.Products[i].Images = images[page.Products[i].ID]
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, page)
 }
 
-// GET
+// GET /search?q=&status=&seller_id=&category_id=&attr.<name>=&min_price=&max_price=&cursor=&limit=
+func (api *Api) handleSearchProducts(w http.ResponseWriter, r *http.Request) {
+	data, problems := product.NewSearchProductsReq(r.URL.Query())
+	if len(problems) == 0 {
+		problems = data.Valid(r.Context())
+	}
+	if len(problems) > 0 {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	page, err := api.ProductService.Search(
+		r.Context(),
+		data.Query,
+		services.ProductFilter{
+			Status:     data.Status,
+			SellerID:   data.SellerID,
+			MinPrice:   data.MinPrice,
+			MaxPrice:   data.MaxPrice,
+			CategoryID: data.CategoryID,
+			Attributes: data.Attributes,
+		},
+		data.Cursor,
+		data.Limit,
+	)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) {
+			_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+				"cursor": err.Error(),
+			})
+			return
+		}
+		var attrErr *services.AttributesError
+		if errors.As(err, &attrErr) {
+			_ = encodeJson(w, r, http.StatusBadRequest, attrErr.Problems)
+			return
+		}
+		if errors.Is(err, services.ErrCategoryNotFound) {
+			_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+				"category_id": "category does not exist",
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "failed to search products",
+		})
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(page.Results))
+	for _, p := range page.Results {
+		ids = append(ids, p.ID)
+	}
+	images, err := api.ProductImagesService.ImagesByProduct(r.Context(), ids...)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "failed to search products",
+		})
+		return
+	}
+	for i := range page.Results {
+		page.Results[i].Images = images[page.Results[i].ID]
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, page)
+}
+
+// GET /{id}
 func (api *Api) handleListProductById(w http.ResponseWriter, r *http.Request) {
-	panic("todo")
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	details, err := api.ProductService.GetProductDetails(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, services.ErrProductNotFound) {
+			_ = encodeJson(w, r, http.StatusNotFound, map[string]any{
+				"error": "product with given id not found",
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	images, err := api.ProductImagesService.ImagesByProduct(r.Context(), id)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+	details.Images = images[id]
+
+	_, details.HasLiveRoom = api.AuctionLobby.Room(id)
+
+	_ = encodeJson(w, r, http.StatusOK, details)
+}
+
+// encodeProductManagementError answers the errors shared by the seller management endpoints.
+func encodeProductManagementError(w http.ResponseWriter, r *http.Request, err error) {
+	var attrErr *services.AttributesError
+	switch {
+	case errors.As(err, &attrErr):
+		_ = encodeJson(w, r, http.StatusUnprocessableEntity, attrErr.Problems)
+	case errors.Is(err, services.ErrProductNotFound):
+		_ = encodeJson(w, r, http.StatusNotFound, map[string]any{
+			"error": "product with given id not found",
+		})
+	case errors.Is(err, services.ErrNotProductSeller):
+		_ = encodeJson(w, r, http.StatusForbidden, map[string]any{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrAuctionNotEditable),
+		errors.Is(err, services.ErrProductLockedAfterBids),
+		errors.Is(err, services.ErrAuctionAlreadyStarted),
+		errors.Is(err, services.ErrProductNotRelistable):
+		_ = encodeJson(w, r, http.StatusConflict, map[string]any{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrInvalidAuctionSchedule):
+		_ = encodeJson(w, r, http.StatusUnprocessableEntity, map[string]any{
+			"auction_end": err.Error(),
+		})
+	default:
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+	}
+}
+
+// PATCH /{id}
+func (api *Api) handleUpdateProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	data, problems, err := decodeValidJson[product.UpdateProductReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	updated, err := api.ProductService.UpdateProduct(r.Context(), userID, id, services.UpdateProductParams{
+		ProductName:  data.ProductName,
+		Description:  data.Description,
+		BasePrice:    data.BasePrice,
+		AuctionStart: data.AuctionStart,
+		AuctionEnd:   data.AuctionEnd,
+		Attributes:   data.Attributes,
+	})
+	if err != nil {
+		encodeProductManagementError(w, r, err)
+		return
+	}
+
+	// The room deadline can not be moved, replace the room and ask clients to reconnect.
+	if data.AuctionStart != nil || data.AuctionEnd != nil {
+		if room, ok := api.AuctionLobby.Room(id); ok {
+			room.Close(services.ErrAuctionRescheduled)
+		}
+		newAuctionRoom := services.NewAuctionRoom(context.Background(), id, updated.AuctionEnd, &api.ProductService, &api.BidsService, &api.AuctionMessagesService)
+		api.AuctionLobby.Open(newAuctionRoom)
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, updated)
+}
+
+// POST /{id}/cancel
+func (api *Api) handleCancelProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	if err := api.ProductService.CancelProduct(r.Context(), userID, id); err != nil {
+		encodeProductManagementError(w, r, err)
+		return
+	}
+
+	// Connected clients get an AuctionCancelled message before the room closes.
+	if room, ok := api.AuctionLobby.Room(id); ok {
+		room.Close(services.ErrAuctionCancelled)
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message": "auction cancelled",
+	})
+}
+
+// POST /{id}/relist
+func (api *Api) handleRelistProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	data, problems, err := decodeValidJson[product.RelistProductReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	newID, err := api.ProductService.RelistProduct(
+		r.Context(),
+		userID,
+		id,
+		data.BasePrice,
+		pgtype.Timestamptz{Time: data.AuctionStart, Valid: true},
+		pgtype.Timestamptz{Time: data.AuctionEnd, Valid: true},
+	)
+	if err != nil {
+		encodeProductManagementError(w, r, err)
+		return
+	}
+
+	newAuctionRoom := services.NewAuctionRoom(context.Background(), newID, data.AuctionEnd, &api.ProductService, &api.BidsService, &api.AuctionMessagesService)
+	api.AuctionLobby.Open(newAuctionRoom)
+
+	_ = encodeJson(w, r, http.StatusCreated, map[string]any{
+		"product_id": newID,
+	})
+}
+
+// POST /{id}/rating
+func (api *Api) handleRateSeller(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	data, problems, err := decodeValidJson[product.RateSellerReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	if err := api.RatingService.RateSeller(r.Context(), userID, id, data.Score, data.Comment); err != nil {
+		switch {
+		case errors.Is(err, services.ErrProductNotFound):
+			_ = encodeJson(w, r, http.StatusNotFound, map[string]any{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrNotAuctionBuyer):
+			_ = encodeJson(w, r, http.StatusForbidden, map[string]any{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrAlreadyRated):
+			_ = encodeJson(w, r, http.StatusConflict, map[string]any{
+				"error": err.Error(),
+			})
+		default:
+			_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+				"error": "unexpected error, try again later.",
+			})
+		}
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusCreated, map[string]any{
+		"message": "seller rated",
+	})
 }
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/gobid/internal/services"
+)
+
+// POST /{id}/images - multipart form with the file in the "image" field.
+func (api *Api) handleUploadProductImage(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	// Leave some room for the multipart boundaries and headers.
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxProductImageBytes+(1<<20))
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			_ = encodeJson(w, r, http.StatusRequestEntityTooLarge, map[string]any{
+				"image": services.ErrImageTooLarge.Error(),
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"image": "a multipart form with an image field is required",
+		})
+		return
+	}
+	defer file.Close()
+
+	img, err := api.ProductImagesService.Upload(r.Context(), userID, id, file)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrImageTooLarge):
+			_ = encodeJson(w, r, http.StatusRequestEntityTooLarge, map[string]any{
+				"image": err.Error(),
+			})
+		case errors.Is(err, services.ErrUnsupportedImage),
+			errors.Is(err, services.ErrImageTooManyPixels):
+			_ = encodeJson(w, r, http.StatusUnprocessableEntity, map[string]any{
+				"image": err.Error(),
+			})
+		case errors.Is(err, services.ErrTooManyImages):
+			_ = encodeJson(w, r, http.StatusConflict, map[string]any{
+				"error": err.Error(),
+			})
+		default:
+			encodeProductManagementError(w, r, err)
+		}
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusCreated, img)
+}
+
+// DELETE /{id}/images/{image_id}
+func (api *Api) handleDeleteProductImage(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	imageID, err := uuid.Parse(chi.URLParam(r, "image_id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	if err := api.ProductImagesService.Delete(r.Context(), userID, id, imageID); err != nil {
+		if errors.Is(err, services.ErrProductImageMissing) {
+			_ = encodeJson(w, r, http.StatusNotFound, map[string]any{
+				"error": err.Error(),
+			})
+			return
+		}
+		encodeProductManagementError(w, r, err)
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message": "image deleted",
+	})
+}
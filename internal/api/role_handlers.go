@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/gobid/internal/authz"
+	"github.com/lohanguedes/gobid/internal/services"
+	"github.com/lohanguedes/gobid/internal/usecase/user"
+)
+
+// POST /users/me/seller
+func (api *Api) handleBecomeSeller(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	if err := api.UserService.BecomeSeller(r.Context(), userID); err != nil {
+		if errors.Is(err, services.ErrEmailNotVerified) {
+			_ = encodeJson(w, r, http.StatusForbidden, map[string]any{
+				"error": err.Error(),
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message": "you can now list products",
+	})
+}
+
+// PUT /admin/users/{id}/role
+func (api *Api) handleSetUserRole(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	data, problems, err := decodeValidJson[user.SetRoleReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	if err := api.UserService.SetRole(r.Context(), userID, id, authz.Role(data.Role)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			_ = encodeJson(w, r, http.StatusNotFound, map[string]any{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrCannotAssignRole):
+			_ = encodeJson(w, r, http.StatusForbidden, map[string]any{
+				"error": err.Error(),
+			})
+		default:
+			_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+				"error": "unexpected error, try again later.",
+			})
+		}
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message": "role updated",
+	})
+}
@@ -1,8 +1,12 @@
 package api
 
 import (
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/lohanguedes/gobid/internal/authz"
+	"github.com/lohanguedes/gobid/internal/services"
 )
 
 func (api *Api) BindRoutes() {
@@ -18,7 +22,12 @@ func (api *Api) BindRoutes() {
 	api.Router.Use(api.Session.LoadAndSave)
 	// api.Router.Use(csrfMiddleware, api.Session.LoadAndSave)
 
-	api.Router.With(api.AuthMiddleware).Get("/ws/subscribe/{product_id}", api.handleSubcribeUserToAuction)
+	// Bots may bid with an api token.
+	api.Router.With(api.AllowTokens(services.ScopeBidsWrite), api.AuthMiddleware).Get("/ws/subscribe/{product_id}", api.handleSubcribeUserToAuction)
+
+	if api.Media != nil {
+		api.Router.Handle("/media/*", http.StripPrefix("/media/", api.Media))
+	}
 
 	// /api/subscribe/10 -> Guitarra ibanez pika
 	api.Router.Route("/api", func(r chi.Router) {
@@ -27,20 +36,117 @@ func (api *Api) BindRoutes() {
 			r.Route("/users", func(r chi.Router) {
 				r.Post("/signup", api.handleSignUpUser)
 				r.Post("/login", api.handleLoginUser)
+				r.Post("/login/2fa", api.handleLoginTwoFactor)
+				r.Post("/password-reset", api.handleRequestPasswordReset)
+				r.Post("/password-reset/confirm", api.handleResetPassword)
+				r.Post("/verify-email", api.handleVerifyEmail)
+				// Sign in with the identity providers of GOBID_OIDC_PROVIDERS_FILE.
+				r.Get("/oidc", api.handleListOIDCProviders)
+				r.Get("/oidc/{provider}/login", api.handleStartOIDCLogin)
+				r.Get("/oidc/{provider}/callback", api.handleOIDCCallback)
 
 				// the user needs to be logged in.
 				r.With(api.AuthMiddleware).Post("/logout", api.handleLogOut)
+
+				r.Route("/me", func(r chi.Router) {
+					r.Use(api.AuthMiddleware)
+					r.Get("/", api.handleGetMe)
+					r.Patch("/", api.handleUpdateMe)
+					r.Post("/password", api.handleChangePassword)
+					r.Post("/verify-email", api.handleSendVerificationEmail)
+					r.Post("/seller", api.handleBecomeSeller)
+					r.Get("/sessions", api.handleListSessions)
+					r.Delete("/sessions", api.handleRevokeOtherSessions)
+					r.Delete("/sessions/{id}", api.handleRevokeSession)
+					r.Get("/api-tokens", api.handleListAPITokens)
+					r.Post("/api-tokens", api.handleCreateAPIToken)
+					r.Delete("/api-tokens/{id}", api.handleRevokeAPIToken)
+					r.Get("/identities", api.handleListIdentities)
+					r.Get("/2fa", api.handleGetTwoFactor)
+					r.Post("/2fa/setup", api.handleSetupTwoFactor)
+					r.Post("/2fa/confirm", api.handleConfirmTwoFactor)
+					r.Post("/2fa/recovery-codes", api.handleRegenerateRecoveryCodes)
+					r.Post("/2fa/disable", api.handleDisableTwoFactor)
+					r.Get("/watchlist", api.handleGetWatchlist)
+					r.Get("/saved-searches", api.handleListSavedSearches)
+					r.Post("/saved-searches", api.handleCreateSavedSearch)
+					r.Delete("/saved-searches/{id}", api.handleDeleteSavedSearch)
+					r.Get("/notification-preferences", api.handleGetNotificationPreferences)
+					r.Patch("/notification-preferences", api.handleUpdateNotificationPreferences)
+				})
+
+				// Public seller page, never shows the email.
+				r.Get("/{id}", api.handleGetUserProfile)
+			})
+
+			r.Route("/notifications", func(r chi.Router) {
+				r.Use(api.AuthMiddleware)
+				r.Get("/", api.handleListNotifications)
+				r.Get("/stream", api.handleNotificationStream)
+				r.Post("/read-all", api.handleMarkAllNotificationsRead)
+				r.Post("/{id}/read", api.handleMarkNotificationRead)
+			})
+
+			// Sellers get the events of their auctions on their own servers.
+			r.Route("/webhooks", func(r chi.Router) {
+				r.Use(api.AuthMiddleware)
+				r.Get("/", api.handleListWebhooks)
+				r.Post("/", api.handleCreateWebhook)
+				r.Delete("/{id}", api.handleDeleteWebhook)
+				r.Get("/{id}/deliveries", api.handleListWebhookDeliveries)
+				r.Post("/{id}/test", api.handleTestWebhook)
 			})
 
+			r.Get("/categories", api.handleListCategories)
+			r.Get("/categories/{id}/attributes", api.handleGetCategoryAttributes)
+
 			r.Route("/products", func(r chi.Router) {
 				r.Group(func(r chi.Router) {
 					r.Get("/list", api.handleListProducts)
+					r.Get("/search", api.handleSearchProducts)
 					r.Get("/{id}", api.handleListProductById)
 				})
 
+				// The integrations of the sellers may use those with an api token.
+				r.Group(func(r chi.Router) {
+					r.Use(api.AllowTokens(services.ScopeProductsWrite), api.AuthMiddleware)
+					// Selling needs the seller role and a verified email, bidding is checked by the BidsService.
+					r.With(api.RequirePermission(authz.PermSellProducts), api.VerifiedMiddleware).Post("/", api.handleCreateProduct)
+
+					// Only the seller of the product or a moderator may use those, see authz.
+					r.Patch("/{id}", api.handleUpdateProduct)
+					r.Post("/{id}/cancel", api.handleCancelProduct)
+					r.With(api.VerifiedMiddleware).Post("/{id}/relist", api.handleRelistProduct)
+					r.Post("/{id}/images", api.handleUploadProductImage)
+					r.Delete("/{id}/images/{image_id}", api.handleDeleteProductImage)
+				})
+
 				r.Group(func(r chi.Router) {
 					r.Use(api.AuthMiddleware)
-					r.Post("/", api.handleCreateProduct)
+					// Any logged in user may watch an auction.
+					r.Post("/{id}/watch", api.handleWatchProduct)
+					r.Delete("/{id}/watch", api.handleUnwatchProduct)
+
+					// Only the buyer of a sold auction, once.
+					r.Post("/{id}/rating", api.handleRateSeller)
+				})
+			})
+
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(api.AuthMiddleware)
+				r.Route("/categories", func(r chi.Router) {
+					r.Use(api.RequirePermission(authz.PermManageCategories))
+					r.Post("/", api.handleCreateCategory)
+					r.Put("/{id}", api.handleUpdateCategory)
+					r.Delete("/{id}", api.handleDeleteCategory)
+				})
+				// The audit of the logins, the failures lock out the email and the ip for a while.
+				r.With(api.RequirePermission(authz.PermViewLoginAttempts)).Get("/login-attempts", api.handleListLoginAttempts)
+				r.With(api.RequirePermission(authz.PermManageRoles)).Put("/users/{id}/role", api.handleSetUserRole)
+				r.Route("/outbox", func(r chi.Router) {
+					r.Use(api.RequirePermission(authz.PermManageOutbox))
+					r.Get("/dead", api.handleListDeadOutboxEvents)
+					r.Post("/{id}/retry", api.handleRetryOutboxEvent)
 				})
 			})
 		})
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/gobid/internal/services"
+	"github.com/lohanguedes/gobid/internal/usecase/product"
+)
+
+// POST /users/me/saved-searches
+func (api *Api) handleCreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	data, problems, err := decodeValidJson[product.SaveSearchReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	search, err := api.SavedSearchService.Create(r.Context(), userID, services.SavedSearchParams{
+		Name:       data.Name,
+		Query:      data.Query,
+		CategoryID: data.CategoryID,
+		MinPrice:   data.MinPrice,
+		MaxPrice:   data.MaxPrice,
+		Attributes: data.Attributes,
+	})
+	if err != nil {
+		var attrErr *services.AttributesError
+		switch {
+		case errors.As(err, &attrErr):
+			_ = encodeJson(w, r, http.StatusUnprocessableEntity, attrErr.Problems)
+		case errors.Is(err, services.ErrCategoryNotFound):
+			_ = encodeJson(w, r, http.StatusUnprocessableEntity, map[string]any{
+				"category_id": "category does not exist",
+			})
+		case errors.Is(err, services.ErrTooManySavedSearches):
+			_ = encodeJson(w, r, http.StatusConflict, map[string]any{
+				"error": err.Error(),
+			})
+		default:
+			_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+				"error": "failed to save the search",
+			})
+		}
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusCreated, search)
+}
+
+// GET /users/me/saved-searches
+func (api *Api) handleListSavedSearches(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	searches, err := api.SavedSearchService.List(r.Context(), userID)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "failed to list the saved searches",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"saved_searches": searches,
+	})
+}
+
+// DELETE /users/me/saved-searches/{id}
+func (api *Api) handleDeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	if err := api.SavedSearchService.Delete(r.Context(), userID, id); err != nil {
+		if errors.Is(err, services.ErrSavedSearchNotFound) {
+			_ = encodeJson(w, r, http.StatusNotFound, map[string]any{
+				"error": "saved search with given id not found",
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message": "saved search deleted",
+	})
+}
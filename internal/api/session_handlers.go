@@ -0,0 +1,108 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/gobid/internal/services"
+)
+
+// GET /users/me/sessions
+func (api *Api) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	sessions, err := api.SessionService.List(r.Context(), userID, api.Session.Token(r.Context()))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "failed to list the sessions",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"sessions": sessions,
+	})
+}
+
+// DELETE /users/me/sessions/{id}
+func (api *Api) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	token, err := api.SessionService.Token(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			_ = encodeJson(w, r, http.StatusNotFound, map[string]any{
+				"error": "session with given id not found",
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	// The current session must go through scs, it would save it again otherwise.
+	if token == api.Session.Token(r.Context()) {
+		err = api.Session.Destroy(r.Context())
+	} else {
+		err = api.Session.Store.Delete(token)
+	}
+	if err == nil {
+		err = api.SessionService.Forget(r.Context(), token)
+	}
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message": "session revoked",
+	})
+}
+
+// DELETE /users/me/sessions
+func (api *Api) handleRevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	if err := api.revokeSessions(r.Context(), userID, api.Session.Token(r.Context())); err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message": "logged out of every other session",
+	})
+}
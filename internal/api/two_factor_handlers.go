@@ -0,0 +1,230 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lohanguedes/gobid/internal/services"
+	"github.com/lohanguedes/gobid/internal/usecase/user"
+)
+
+const (
+	// How long the user has to type the code once the password was right.
+	twoFactorLoginTTL = 5 * time.Minute
+	// The wrong codes allowed before the password must be typed again, they
+	// count toward the lockout of the email too.
+	maxTwoFactorFailures = 5
+)
+
+func (api *Api) clearPendingTwoFactor(r *http.Request) {
+	api.Session.Remove(r.Context(), "pendingTwoFactorUserId")
+	api.Session.Remove(r.Context(), "pendingTwoFactorAt")
+	api.Session.Remove(r.Context(), "pendingTwoFactorFailures")
+}
+
+// POST /users/login/2fa
+func (api *Api) handleLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	data, problems, err := decodeValidJson[user.TwoFactorCodeReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	userID, ok := api.Session.Get(r.Context(), "pendingTwoFactorUserId").(uuid.UUID)
+	startedAt := time.Unix(api.Session.GetInt64(r.Context(), "pendingTwoFactorAt"), 0)
+	if !ok || time.Since(startedAt) > twoFactorLoginTTL {
+		api.clearPendingTwoFactor(r)
+		_ = encodeJson(w, r, http.StatusUnauthorized, map[string]any{
+			"message": "log in with your email and password first",
+		})
+		return
+	}
+
+	if err := api.TwoFactorService.Login(r.Context(), userID, data.Code, clientIP(r)); err != nil {
+		var lockedErr *services.LoginLockedError
+		if errors.As(err, &lockedErr) {
+			api.clearPendingTwoFactor(r)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			_ = encodeJson(w, r, http.StatusTooManyRequests, map[string]string{
+				"error": lockedErr.Error(),
+			})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidTwoFactorCode) {
+			failures := api.Session.GetInt(r.Context(), "pendingTwoFactorFailures") + 1
+			if failures >= maxTwoFactorFailures {
+				api.clearPendingTwoFactor(r)
+				_ = encodeJson(w, r, http.StatusUnauthorized, map[string]any{
+					"message": "too many invalid codes, log in again",
+				})
+				return
+			}
+			api.Session.Put(r.Context(), "pendingTwoFactorFailures", failures)
+			_ = encodeJson(w, r, http.StatusUnprocessableEntity, map[string]any{
+				"code": err.Error(),
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	api.clearPendingTwoFactor(r)
+	if err := api.startSession(r, userID); err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected internal server error",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message": "logged in successfully",
+	})
+}
+
+// GET /users/me/2fa
+func (api *Api) handleGetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	status, err := api.TwoFactorService.Status(r.Context(), userID)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, status)
+}
+
+// POST /users/me/2fa/setup
+func (api *Api) handleSetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	setup, err := api.TwoFactorService.Setup(r.Context(), userID)
+	if err != nil {
+		encodeTwoFactorError(w, r, err)
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, setup)
+}
+
+// POST /users/me/2fa/confirm
+func (api *Api) handleConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	data, problems, err := decodeValidJson[user.TwoFactorCodeReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	codes, err := api.TwoFactorService.Confirm(r.Context(), userID, data.Code)
+	if err != nil {
+		encodeTwoFactorError(w, r, err)
+		return
+	}
+
+	// The only time the recovery codes are shown.
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message":        "two factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// POST /users/me/2fa/recovery-codes
+func (api *Api) handleRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	data, problems, err := decodeValidJson[user.TwoFactorCodeReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	codes, err := api.TwoFactorService.RegenerateRecoveryCodes(r.Context(), userID, data.Code)
+	if err != nil {
+		encodeTwoFactorError(w, r, err)
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"recovery_codes": codes,
+	})
+}
+
+// POST /users/me/2fa/disable
+func (api *Api) handleDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	data, problems, err := decodeValidJson[user.TwoFactorCodeReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	if err := api.TwoFactorService.Disable(r.Context(), userID, data.Code); err != nil {
+		encodeTwoFactorError(w, r, err)
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message": "two factor authentication disabled",
+	})
+}
+
+func encodeTwoFactorError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrTwoFactorEnabled),
+		errors.Is(err, services.ErrTwoFactorNotSetUp),
+		errors.Is(err, services.ErrTwoFactorNotEnabled):
+		_ = encodeJson(w, r, http.StatusConflict, map[string]any{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		_ = encodeJson(w, r, http.StatusUnprocessableEntity, map[string]any{
+			"code": err.Error(),
+		})
+	default:
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+	}
+}
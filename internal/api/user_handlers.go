@@ -2,13 +2,22 @@ package api
 
 import (
 	"errors"
-	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/gobid/internal/services"
 	"github.com/lohanguedes/gobid/internal/store/pgstore"
 	"github.com/lohanguedes/gobid/internal/usecase/user"
 )
 
+// How many listings a public profile shows.
+const profileListings = 20
+
 func (api *Api) handleSignUpUser(w http.ResponseWriter, r *http.Request) {
 	data, problems, err := decodeValidJson[user.CreateUserReq](r)
 	if err != nil {
@@ -24,13 +33,22 @@ func (api *Api) handleSignUpUser(w http.ResponseWriter, r *http.Request) {
 		data.Bio,
 	)
 	if err != nil {
-		fmt.Println(err.Error())
 		if errors.Is(err, pgstore.ErrDuplicateEmail) {
 			_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
 				"error": "invalid data: duplicate email",
 			})
 			return
 		}
+		slog.Error("Failed to create the user", "error", err)
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	// The account is made, the user can ask for another email if this one fails.
+	if err := api.EmailVerificationService.Send(r.Context(), id); err != nil {
+		slog.Error("Failed to send the verification email", "user_id", id, "error", err)
 	}
 
 	_ = encodeJson(w, r, http.StatusCreated, map[string]any{
@@ -45,7 +63,7 @@ func (api *Api) handleLoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := api.UserService.Authenticate(r.Context(), data.Email, data.Password)
+	id, err := api.UserService.Authenticate(r.Context(), data.Email, data.Password, clientIP(r))
 	if err != nil {
 		if errors.Is(err, pgstore.ErrInvalidCredentials) {
 			_ = encodeJson(w, r, http.StatusUnprocessableEntity, map[string]string{
@@ -53,13 +71,28 @@ func (api *Api) handleLoginUser(w http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
+		var lockedErr *services.LoginLockedError
+		if errors.As(err, &lockedErr) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			_ = encodeJson(w, r, http.StatusTooManyRequests, map[string]string{
+				"error": lockedErr.Error(),
+			})
+			return
+		}
+		slog.Error("Failed to authenticate the user", "error", err)
 		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
+			"error": "unexpected internal server error",
 		})
 		return
 	}
 
-	err = api.Session.RenewToken(r.Context())
+	api.completeLogin(w, r, id)
+}
+
+// completeLogin logs the request in as the user once they proved who they
+// are, or asks for the code of their authenticator app first.
+func (api *Api) completeLogin(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	twoFactor, err := api.TwoFactorService.Enabled(r.Context(), id)
 	if err != nil {
 		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]string{
 			"error": "unexpected internal server error",
@@ -67,7 +100,30 @@ func (api *Api) handleLoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	api.Session.Put(r.Context(), "authenticatedUserId", id)
+	// The session is only authenticated once POST /login/2fa gets a valid code.
+	if twoFactor {
+		if err := api.Session.RenewToken(r.Context()); err != nil {
+			_ = encodeJson(w, r, http.StatusInternalServerError, map[string]string{
+				"error": "unexpected internal server error",
+			})
+			return
+		}
+		api.clearPendingTwoFactor(r)
+		api.Session.Put(r.Context(), "pendingTwoFactorUserId", id)
+		api.Session.Put(r.Context(), "pendingTwoFactorAt", time.Now().Unix())
+		_ = encodeJson(w, r, http.StatusOK, map[string]any{
+			"message":             "enter the code of your authenticator app",
+			"two_factor_required": true,
+		})
+		return
+	}
+
+	if err := api.startSession(r, id); err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]string{
+			"error": "unexpected internal server error",
+		})
+		return
+	}
 
 	_ = encodeJson(w, r, http.StatusOK, map[string]string{
 		"message": "logged in successfully",
@@ -75,6 +131,13 @@ func (api *Api) handleLoginUser(w http.ResponseWriter, r *http.Request) {
 }
 
 func (api *Api) handleLogOut(w http.ResponseWriter, r *http.Request) {
+	if err := api.SessionService.Forget(r.Context(), api.Session.Token(r.Context())); err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]string{
+			"error": "unexpected internal server error",
+		})
+		return
+	}
+
 	err := api.Session.RenewToken(r.Context())
 	if err != nil {
 		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]string{
@@ -88,3 +151,282 @@ func (api *Api) handleLogOut(w http.ResponseWriter, r *http.Request) {
 		"message": "logged out successfully",
 	})
 }
+
+// GET /users/me
+func (api *Api) handleGetMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	account, err := api.UserService.Account(r.Context(), userID)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"user": account,
+	})
+}
+
+// PATCH /users/me
+func (api *Api) handleUpdateMe(w http.ResponseWriter, r *http.Request) {
+	data, problems, err := decodeValidJson[user.UpdateUserReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	account, err := api.UserService.UpdateAccount(r.Context(), userID, data.UserName, data.Bio)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"user": account,
+	})
+}
+
+// GET /users/{id}
+func (api *Api) handleGetUserProfile(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	profile, err := api.UserService.PublicProfile(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			_ = encodeJson(w, r, http.StatusNotFound, map[string]any{
+				"error": "user with given id not found",
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	ratings, err := api.RatingService.Summary(r.Context(), id)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	// Only the first page, the rest come from /products/list?seller_id=.
+	listings, err := api.ProductService.ListProducts(
+		r.Context(),
+		services.ProductFilter{SellerID: &id},
+		services.SortNewest,
+		"",
+		profileListings,
+	)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(listings.Products))
+	for _, p := range listings.Products {
+		ids = append(ids, p.ID)
+	}
+	images, err := api.ProductImagesService.ImagesByProduct(r.Context(), ids...)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+	for i := range listings.Products {
+		listings.Products[i].Images = images[listings.Products[i].ID]
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"user":     profile,
+		"ratings":  ratings,
+		"listings": listings,
+	})
+}
+
+// POST /users/me/password
+func (api *Api) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	data, problems, err := decodeValidJson[user.ChangePasswordReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	err = api.UserService.ChangePassword(r.Context(), userID, data.CurrentPassword, data.NewPassword)
+	if err != nil {
+		if errors.Is(err, pgstore.ErrInvalidCredentials) {
+			_ = encodeJson(w, r, http.StatusUnprocessableEntity, map[string]any{
+				"current_password": "password is incorrect",
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	// Whoever knew the old password is logged out, this session stays with a new token.
+	if err := api.revokeSessions(r.Context(), userID, api.Session.Token(r.Context())); err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "password changed but failed to log out the other sessions",
+		})
+		return
+	}
+	if err := api.renewSession(r); err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected internal server error",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message": "password changed",
+	})
+}
+
+// POST /users/password-reset
+func (api *Api) handleRequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	data, problems, err := decodeValidJson[user.RequestPasswordResetReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	if err := api.PasswordResetService.Request(r.Context(), data.Email); err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	// The same answer whether or not the email has an account.
+	_ = encodeJson(w, r, http.StatusAccepted, map[string]any{
+		"message": "if the email has an account, a reset link was sent to it",
+	})
+}
+
+// POST /users/password-reset/confirm
+func (api *Api) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	data, problems, err := decodeValidJson[user.ResetPasswordReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	userID, err := api.PasswordResetService.Reset(r.Context(), data.Token, data.Password)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+				"token": err.Error(),
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	if err := api.revokeSessions(r.Context(), userID, ""); err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "password changed but failed to log out the other sessions",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message": "password changed, log in with the new one",
+	})
+}
+
+// POST /users/me/verify-email
+func (api *Api) handleSendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	if err := api.EmailVerificationService.Send(r.Context(), userID); err != nil {
+		if errors.Is(err, services.ErrEmailAlreadyVerified) {
+			_ = encodeJson(w, r, http.StatusConflict, map[string]any{
+				"error": err.Error(),
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusAccepted, map[string]any{
+		"message": "a verification link was sent to your email",
+	})
+}
+
+// POST /users/verify-email
+func (api *Api) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	data, problems, err := decodeValidJson[user.VerifyEmailReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	if _, err := api.EmailVerificationService.Verify(r.Context(), data.Token); err != nil {
+		if errors.Is(err, services.ErrInvalidVerificationToken) {
+			_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+				"token": err.Error(),
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error, try again later.",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message": "email verified",
+	})
+}
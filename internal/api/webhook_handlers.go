@@ -0,0 +1,169 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/gobid/internal/services"
+	"github.com/lohanguedes/gobid/internal/usecase/webhook"
+)
+
+// GET /webhooks
+func (api *Api) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	endpoints, err := api.WebhookService.List(r.Context(), userID)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "failed to list webhooks",
+		})
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"webhooks": endpoints,
+	})
+}
+
+// POST /webhooks
+func (api *Api) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	data, problems, err := decodeValidJson[webhook.CreateWebhookReq](r)
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, problems)
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	endpoint, err := api.WebhookService.Create(r.Context(), userID, data.URL, data.Events)
+	if err != nil {
+		if errors.Is(err, services.ErrTooManyWebhooks) {
+			_ = encodeJson(w, r, http.StatusConflict, map[string]any{
+				"error": err.Error(),
+			})
+			return
+		}
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "failed to create webhook",
+		})
+		return
+	}
+
+	// The secret is only shown here.
+	_ = encodeJson(w, r, http.StatusCreated, map[string]any{
+		"webhook": endpoint,
+	})
+}
+
+// DELETE /webhooks/{id}
+func (api *Api) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	if err := api.WebhookService.Delete(r.Context(), userID, id); err != nil {
+		encodeWebhookError(w, r, err)
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"message": "webhook deleted",
+	})
+}
+
+// GET /webhooks/{id}/deliveries
+func (api *Api) handleListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	deliveries, err := api.WebhookService.Deliveries(r.Context(), userID, id)
+	if err != nil {
+		encodeWebhookError(w, r, err)
+		return
+	}
+
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"deliveries": deliveries,
+	})
+}
+
+// POST /webhooks/{id}/test
+func (api *Api) handleTestWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		_ = encodeJson(w, r, http.StatusBadRequest, map[string]any{
+			"error": "failed to parse uuid - must be a valid uuid",
+		})
+		return
+	}
+
+	userID, ok := authenticatedUserID(r.Context())
+	if !ok {
+		_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+			"error": "unexpected error try again later",
+		})
+		return
+	}
+
+	delivery, err := api.WebhookService.SendTest(r.Context(), userID, id)
+	if err != nil {
+		encodeWebhookError(w, r, err)
+		return
+	}
+
+	// A failed delivery is still a result, its error is in the log entry.
+	_ = encodeJson(w, r, http.StatusOK, map[string]any{
+		"delivery": delivery,
+	})
+}
+
+func encodeWebhookError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		_ = encodeJson(w, r, http.StatusNotFound, map[string]any{
+			"error": "webhook with given id not found",
+		})
+		return
+	}
+	_ = encodeJson(w, r, http.StatusInternalServerError, map[string]any{
+		"error": "unexpected error, try again later.",
+	})
+}
@@ -0,0 +1,94 @@
+// Package authz holds who may do what. The routes check the permissions of
+// the role of the user with the middleware of the api, the services check
+// the actions on a given product or user with the policies below.
+package authz
+
+import "github.com/google/uuid"
+
+type Role string
+
+// Every role can do what the roles before it can.
+const (
+	RoleUser      Role = "user"
+	RoleSeller    Role = "seller"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+type Permission string
+
+const (
+	PermPlaceBids    Permission = "bids:place"
+	PermSellProducts Permission = "products:sell"
+	// Edit and cancel the auctions of the other sellers.
+	PermModerateProducts  Permission = "products:moderate"
+	PermViewLoginAttempts Permission = "login_attempts:read"
+	PermManageCategories  Permission = "categories:manage"
+	PermManageOutbox      Permission = "outbox:manage"
+	PermManageRoles       Permission = "users:manage_roles"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleUser:      {PermPlaceBids},
+	RoleSeller:    {PermPlaceBids, PermSellProducts},
+	RoleModerator: {PermPlaceBids, PermSellProducts, PermModerateProducts, PermViewLoginAttempts},
+	RoleAdmin: {
+		PermPlaceBids, PermSellProducts, PermModerateProducts, PermViewLoginAttempts,
+		PermManageCategories, PermManageOutbox, PermManageRoles,
+	},
+}
+
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can reports whether the role has the permission, unknown roles have none.
+func (r Role) Can(p Permission) bool {
+	for _, perm := range rolePermissions[r] {
+		if perm == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Subject is the user an action is made by.
+type Subject struct {
+	UserID uuid.UUID
+	Role   Role
+}
+
+func (s Subject) Can(p Permission) bool {
+	return s.Role.Can(p)
+}
+
+// CanManageProduct is for the changes to a listing: its seller, as long as
+// they may still sell, or a moderator.
+func CanManageProduct(s Subject, sellerID uuid.UUID) bool {
+	if s.UserID == sellerID {
+		return s.Can(PermSellProducts)
+	}
+	return s.Can(PermModerateProducts)
+}
+
+// CanRelistProduct is only for the seller, the new auction is theirs.
+func CanRelistProduct(s Subject, sellerID uuid.UUID) bool {
+	return s.UserID == sellerID && s.Can(PermSellProducts)
+}
+
+// CanAnswerQuestions is only for the seller, the answers speak for them.
+func CanAnswerQuestions(s Subject, sellerID uuid.UUID) bool {
+	return s.UserID == sellerID
+}
+
+// CanBid keeps the sellers from raising the price of their own auctions.
+func CanBid(s Subject, sellerID uuid.UUID) bool {
+	return s.UserID != sellerID && s.Can(PermPlaceBids)
+}
+
+// CanAssignRole is for the admins, on anyone but themselves so the last admin
+// cannot lock everyone out.
+func CanAssignRole(s Subject, userID uuid.UUID, role Role) bool {
+	return s.UserID != userID && role.Valid() && s.Can(PermManageRoles)
+}
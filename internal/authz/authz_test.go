@@ -0,0 +1,146 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRolePermissions(t *testing.T) {
+	all := []Permission{
+		PermPlaceBids, PermSellProducts, PermModerateProducts, PermViewLoginAttempts,
+		PermManageCategories, PermManageOutbox, PermManageRoles,
+	}
+	tests := []struct {
+		role Role
+		want []Permission
+	}{
+		{RoleUser, []Permission{PermPlaceBids}},
+		{RoleSeller, []Permission{PermPlaceBids, PermSellProducts}},
+		{RoleModerator, []Permission{PermPlaceBids, PermSellProducts, PermModerateProducts, PermViewLoginAttempts}},
+		{RoleAdmin, all},
+		{"", nil},
+		{"superuser", nil},
+		{"Admin", nil},
+	}
+
+	for _, tt := range tests {
+		want := make(map[Permission]bool)
+		for _, p := range tt.want {
+			want[p] = true
+		}
+		if valid := tt.want != nil; tt.role.Valid() != valid {
+			t.Errorf("%q.Valid() = %t, want %t", tt.role, !valid, valid)
+		}
+		for _, p := range all {
+			if got := tt.role.Can(p); got != want[p] {
+				t.Errorf("%q.Can(%s) = %t, want %t", tt.role, p, got, want[p])
+			}
+		}
+	}
+}
+
+func TestProductPolicies(t *testing.T) {
+	seller := uuid.New()
+	other := uuid.New()
+
+	tests := []struct {
+		name    string
+		subject Subject
+		manage  bool
+		relist  bool
+		answer  bool
+		bid     bool
+	}{
+		{
+			name:    "seller of the product",
+			subject: Subject{UserID: seller, Role: RoleSeller},
+			manage:  true, relist: true, answer: true,
+		},
+		{
+			// Their listings stay theirs to answer, but no longer to change.
+			name:    "seller demoted to user",
+			subject: Subject{UserID: seller, Role: RoleUser},
+			answer:  true,
+		},
+		{
+			name:    "another seller",
+			subject: Subject{UserID: other, Role: RoleSeller},
+			bid:     true,
+		},
+		{
+			name:    "user",
+			subject: Subject{UserID: other, Role: RoleUser},
+			bid:     true,
+		},
+		{
+			name:    "moderator",
+			subject: Subject{UserID: other, Role: RoleModerator},
+			manage:  true, bid: true,
+		},
+		{
+			name:    "admin",
+			subject: Subject{UserID: other, Role: RoleAdmin},
+			manage:  true, bid: true,
+		},
+		{
+			name:    "admin selling",
+			subject: Subject{UserID: seller, Role: RoleAdmin},
+			manage:  true, relist: true, answer: true,
+		},
+		{
+			name:    "unknown role",
+			subject: Subject{UserID: other, Role: "superuser"},
+		},
+		{
+			name:    "unknown role on their own product",
+			subject: Subject{UserID: seller, Role: "superuser"},
+			answer:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanManageProduct(tt.subject, seller); got != tt.manage {
+				t.Errorf("CanManageProduct = %t, want %t", got, tt.manage)
+			}
+			if got := CanRelistProduct(tt.subject, seller); got != tt.relist {
+				t.Errorf("CanRelistProduct = %t, want %t", got, tt.relist)
+			}
+			if got := CanAnswerQuestions(tt.subject, seller); got != tt.answer {
+				t.Errorf("CanAnswerQuestions = %t, want %t", got, tt.answer)
+			}
+			if got := CanBid(tt.subject, seller); got != tt.bid {
+				t.Errorf("CanBid = %t, want %t", got, tt.bid)
+			}
+		})
+	}
+}
+
+func TestCanAssignRole(t *testing.T) {
+	admin := Subject{UserID: uuid.New(), Role: RoleAdmin}
+	target := uuid.New()
+
+	tests := []struct {
+		name    string
+		subject Subject
+		userID  uuid.UUID
+		role    Role
+		want    bool
+	}{
+		{"admin promotes a user", admin, target, RoleModerator, true},
+		{"admin demotes a user", admin, target, RoleUser, true},
+		{"admin makes another admin", admin, target, RoleAdmin, true},
+		{"admin changes their own role", admin, admin.UserID, RoleUser, false},
+		{"admin keeps their own role", admin, admin.UserID, RoleAdmin, false},
+		{"unknown role", admin, target, "superuser", false},
+		{"moderator", Subject{UserID: uuid.New(), Role: RoleModerator}, target, RoleSeller, false},
+		{"user promotes themselves", Subject{UserID: target, Role: RoleUser}, target, RoleAdmin, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanAssignRole(tt.subject, tt.userID, tt.role); got != tt.want {
+			t.Errorf("%s: got %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
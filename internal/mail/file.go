@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// FileMailer writes every message to an .eml file under dir instead of
+// sending it, for development and tests.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	raw, err := encode(m.from, msg)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(m.dir, fmt.Sprintf("%d-*.eml", time.Now().UnixNano()))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(raw); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
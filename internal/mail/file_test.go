@@ -0,0 +1,103 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer, err := NewFileMailer(dir, "gobid <no-reply@gobid.example.com>")
+	if err != nil {
+		t.Fatalf("NewFileMailer: %v", err)
+	}
+
+	sent := Message{
+		To:      "ada@example.com",
+		Subject: "Você foi superado",
+		Text:    "Hi Ada,\n\na line that is much longer than the seventy six characters quoted-printable allows on a line\n",
+		HTML:    `<p>Hi Ada, <a href="https://gobid.example.com/?a=1&amp;b=2">open</a></p>`,
+	}
+	for range 2 {
+		if err := mailer.Send(context.Background(), sent); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want one per message", len(files))
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	msg, err := mail.ReadMessage(f)
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if got := msg.Header.Get("From"); got != "gobid <no-reply@gobid.example.com>" {
+		t.Errorf("got From %q", got)
+	}
+	if got := msg.Header.Get("To"); got != sent.To {
+		t.Errorf("got To %q, want %q", got, sent.To)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != sent.Subject {
+		t.Errorf("got Subject %q (%v), want %q", subject, err, sent.Subject)
+	}
+	if _, err := msg.Header.Date(); err != nil {
+		t.Errorf("Date: %v", err)
+	}
+	if msg.Header.Get("Message-ID") == "" {
+		t.Error("the message has no Message-ID")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("got Content-Type %q (%v), want multipart/alternative", mediaType, err)
+	}
+
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", sent.Text},
+		{"text/html; charset=utf-8", sent.HTML},
+	} {
+		part, err := parts.NextRawPart()
+		if err != nil {
+			t.Fatalf("NextRawPart: %v", err)
+		}
+		if got := part.Header.Get("Content-Type"); got != want.contentType {
+			t.Errorf("got part %q, want %q", got, want.contentType)
+		}
+		if got := part.Header.Get("Content-Transfer-Encoding"); got != "quoted-printable" {
+			t.Errorf("got encoding %q, want quoted-printable", got)
+		}
+
+		content, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The line breaks are CRLF on the wire.
+		if got := strings.ReplaceAll(string(content), "\r\n", "\n"); got != want.content {
+			t.Errorf("got %s part %q, want %q", want.contentType, got, want.content)
+		}
+	}
+	if _, err := parts.NextRawPart(); err != io.EOF {
+		t.Errorf("got %v after the two parts, want io.EOF", err)
+	}
+}
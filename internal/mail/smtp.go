@@ -0,0 +1,40 @@
+package mail
+
+import (
+	"context"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+)
+
+// SMTPMailer sends the messages through an SMTP server, STARTTLS is used
+// when the server offers it. Without a username no authentication is done,
+// which is what local SMTP catchers like Mailpit expect.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// The From header of every message, like "gobid <no-reply@example.com>".
+	From string
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	raw, err := encode(m.From, msg)
+	if err != nil {
+		return err
+	}
+
+	// The envelope only takes the address.
+	from, err := netmail.ParseAddress(m.From)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	return smtp.SendMail(net.JoinHostPort(m.Host, m.Port), auth, from.Address, []string{msg.To}, raw)
+}
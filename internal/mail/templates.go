@@ -0,0 +1,79 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	texttemplate "text/template"
+)
+
+var ErrUnknownTemplate = errors.New("mail: unknown template")
+
+// Every message has a <name>.txt.tmpl defining the "subject" and "text"
+// templates and a <name>.html.tmpl defining "content", rendered inside the
+// layout.html.tmpl.
+//
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+type Templates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+func NewTemplates() (*Templates, error) {
+	names, err := fs.Glob(templateFS, "templates/*.txt.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Templates{
+		text: make(map[string]*texttemplate.Template, len(names)),
+		html: make(map[string]*htmltemplate.Template, len(names)),
+	}
+	for _, file := range names {
+		name := strings.TrimSuffix(strings.TrimPrefix(file, "templates/"), ".txt.tmpl")
+
+		text, err := texttemplate.ParseFS(templateFS, file)
+		if err != nil {
+			return nil, err
+		}
+		html, err := htmltemplate.ParseFS(templateFS, "templates/layout.html.tmpl", "templates/"+name+".html.tmpl")
+		if err != nil {
+			return nil, err
+		}
+
+		t.text[name] = text
+		t.html[name] = html
+	}
+
+	return t, nil
+}
+
+// Render builds the message of the named template, the recipient is left to the caller.
+func (t *Templates) Render(name string, data any) (Message, error) {
+	text, ok := t.text[name]
+	if !ok {
+		return Message{}, ErrUnknownTemplate
+	}
+
+	var subject, body, html bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := text.ExecuteTemplate(&body, "text", data); err != nil {
+		return Message{}, err
+	}
+	if err := t.html[name].ExecuteTemplate(&html, "layout", data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(body.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,110 @@
+package mail
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+// The data the services render the templates with, a field a template uses
+// and the service does not have fails the render.
+type notificationEmail struct {
+	UserName string
+	Title    string
+	Body     string
+}
+
+type linkEmail struct {
+	UserName string
+	Title    string
+	URL      string
+	ValidFor string
+}
+
+func TestTemplates(t *testing.T) {
+	templates, err := NewTemplates()
+	if err != nil {
+		t.Fatalf("NewTemplates: %v", err)
+	}
+
+	notification := notificationEmail{
+		UserName: "Ada <script>",
+		Title:    "Notification title",
+		Body:     "Notification body & more",
+	}
+	link := linkEmail{
+		UserName: "Ada <script>",
+		Title:    "Link title",
+		URL:      "https://gobid.example.com/reset?token=abc&x=1",
+		ValidFor: "1 hour",
+	}
+	tests := map[string]any{
+		"auction_won":      notification,
+		"item_sold":        notification,
+		"outbid":           notification,
+		"password_changed": notification,
+		"welcome":          notification,
+		"password_reset":   link,
+		"verify_email":     link,
+	}
+
+	// Every template is covered, the new ones must be added above.
+	files, err := fs.Glob(templateFS, "templates/*.txt.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(tests) {
+		t.Errorf("got %d templates, the test covers %d", len(files), len(tests))
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			msg, err := templates.Render(name, data)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+
+			if msg.Subject == "" || strings.Contains(msg.Subject, "\n") {
+				t.Errorf("got subject %q, want one line", msg.Subject)
+			}
+			for part, content := range map[string]string{"text": msg.Text, "html": msg.HTML} {
+				if strings.Contains(content, "<no value>") {
+					t.Errorf("the %s has a missing value:\n%s", part, content)
+				}
+			}
+
+			if !strings.HasPrefix(msg.Text, "Hi Ada <script>,") {
+				t.Errorf("the text does not greet the user:\n%s", msg.Text)
+			}
+			if strings.Contains(msg.HTML, "<script>") || !strings.Contains(msg.HTML, "Ada &lt;script&gt;") {
+				t.Errorf("the html does not escape the user name:\n%s", msg.HTML)
+			}
+
+			switch data := data.(type) {
+			case notificationEmail:
+				if !strings.Contains(msg.Text, data.Body) || !strings.Contains(msg.HTML, "Notification body &amp; more") {
+					t.Errorf("the body is missing:\n%s\n%s", msg.Text, msg.HTML)
+				}
+			case linkEmail:
+				if !strings.Contains(msg.Text, data.URL) || !strings.Contains(msg.HTML, `href="https://gobid.example.com/reset?token=abc&amp;x=1"`) {
+					t.Errorf("the link is missing:\n%s\n%s", msg.Text, msg.HTML)
+				}
+				if !strings.Contains(msg.Text, data.ValidFor) || !strings.Contains(msg.HTML, data.ValidFor) {
+					t.Errorf("how long the link works is missing:\n%s\n%s", msg.Text, msg.HTML)
+				}
+			}
+		})
+	}
+}
+
+func TestTemplatesUnknown(t *testing.T) {
+	templates, err := NewTemplates()
+	if err != nil {
+		t.Fatalf("NewTemplates: %v", err)
+	}
+
+	if _, err := templates.Render("layout", notificationEmail{}); !errors.Is(err, ErrUnknownTemplate) {
+		t.Fatalf("got %v, want ErrUnknownTemplate", err)
+	}
+}
@@ -0,0 +1,246 @@
+// Package oidc implements the part of OpenID Connect the "Sign in with"
+// buttons need: the authorization code flow with PKCE and the verification of
+// the ID tokens against the keys the provider publishes.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The responses of the providers are small, a bigger one is a broken provider.
+const maxResponseSize = 1 << 20
+
+var (
+	ErrInvalidIDToken = errors.New("oidc: invalid id token")
+
+	nameRX = regexp.MustCompile("^[a-z0-9][a-z0-9-]{0,31}$")
+)
+
+// Config is one identity provider, as registered on its side.
+type Config struct {
+	// Used in the urls of the login, such as google or keycloak.
+	Name string `json:"name"`
+	// Shown on the button, the name when empty.
+	DisplayName  string `json:"display_name"`
+	Issuer       string `json:"issuer"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	// openid, email and profile when empty.
+	Scopes []string `json:"scopes"`
+}
+
+func (c Config) Valid() error {
+	if !nameRX.MatchString(c.Name) {
+		return fmt.Errorf("oidc: invalid provider name %q, use lowercase letters, digits and dashes", c.Name)
+	}
+	u, err := url.Parse(c.Issuer)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("oidc: provider %s: the issuer must be an absolute http or https url", c.Name)
+	}
+	if c.ClientID == "" {
+		return fmt.Errorf("oidc: provider %s: the client_id is missing", c.Name)
+	}
+	return nil
+}
+
+// Claims are the claims of a verified ID token the logins use.
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider talks to one identity provider. Its endpoints are discovered on
+// the first login, so the server starts while the provider is down.
+type Provider struct {
+	config      Config
+	redirectURL string
+	client      *http.Client
+
+	mu   sync.Mutex
+	meta *metadata
+	keys *keySet
+}
+
+// NewProvider returns the provider of the valid config c, redirectURL is the
+// callback registered on the provider.
+func NewProvider(c Config, redirectURL string, client *http.Client) *Provider {
+	if len(c.Scopes) == 0 {
+		c.Scopes = []string{"openid", "email", "profile"}
+	}
+	if c.DisplayName == "" {
+		c.DisplayName = c.Name
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Provider{config: c, redirectURL: redirectURL, client: client}
+}
+
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+func (p *Provider) DisplayName() string {
+	return p.config.DisplayName
+}
+
+// RandomString returns a random url safe string, for the states, the nonces
+// and the PKCE verifiers.
+func RandomString() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// Challenge is the S256 PKCE challenge of the verifier.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthURL is the login page of the provider the user is sent to.
+func (p *Provider) AuthURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	link, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+	query := link.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.redirectURL)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", Challenge(verifier))
+	query.Set("code_challenge_method", "S256")
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
+
+// Exchange trades the code the provider redirected back with for the claims
+// of the verified ID token.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (Claims, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURL},
+		"code_verifier": {verifier},
+		"client_id":     {p.config.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Claims{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.doJSON(req, &token)
+	if err != nil {
+		return Claims{}, err
+	}
+	if status != http.StatusOK || token.Error != "" {
+		return Claims{}, fmt.Errorf("oidc: token request failed with %d: %s", status, strings.TrimSpace(token.Error+" "+token.ErrorDescription))
+	}
+	if token.IDToken == "" {
+		return Claims{}, errors.New("oidc: the token response has no id_token")
+	}
+
+	return p.verify(ctx, meta, token.IDToken, nonce)
+}
+
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	wellKnown := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta metadata
+	status, err := p.doJSON(req, &meta)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("oidc: discovery of %s failed with %d", p.config.Issuer, status)
+	}
+	// Otherwise another issuer could mint the tokens of this one.
+	if meta.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("oidc: %s reports the issuer %q", p.config.Issuer, meta.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc: the discovery document of %s is incomplete", p.config.Issuer)
+	}
+
+	p.meta = &meta
+	return p.meta, nil
+}
+
+// doJSON decodes the body of the response to v whatever its status, the
+// errors of the providers are JSON too.
+func (p *Provider) doJSON(req *http.Request, v any) (int, error) {
+	res, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return 0, err
+	}
+	if err := json.Unmarshal(body, v); err != nil && res.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("oidc: invalid response from %s: %w", req.URL.Host, err)
+	}
+
+	return res.StatusCode, nil
+}
@@ -0,0 +1,422 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testClientID     = "gobid"
+	testClientSecret = "s3cret"
+	testRedirectURL  = "http://localhost:3080/api/v1/users/oidc/mock/callback"
+)
+
+// mockProvider is an identity provider with the endpoints of the code flow,
+// it checks the PKCE verifier and signs the ID tokens with its own keys.
+type mockProvider struct {
+	srv    *httptest.Server
+	issuer string
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+
+	mu sync.Mutex
+	// The pending logins, by code.
+	logins map[string]mockLogin
+	// Changes the claims and the header of the next tokens.
+	tamper func(header, claims map[string]any)
+	// Changes the signed token.
+	tamperToken func(token string) string
+}
+
+type mockLogin struct {
+	nonce     string
+	challenge string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &mockProvider{rsaKey: rsaKey, ecKey: ecKey, logins: make(map[string]mockLogin)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.discovery)
+	mux.HandleFunc("/jwks", m.jwks)
+	mux.HandleFunc("/token", m.token)
+	m.srv = httptest.NewServer(mux)
+	m.issuer = m.srv.URL
+	t.Cleanup(m.srv.Close)
+	return m
+}
+
+func (m *mockProvider) provider() *Provider {
+	return NewProvider(Config{
+		Name:         "mock",
+		Issuer:       m.srv.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+	}, testRedirectURL, m.srv.Client())
+}
+
+func (m *mockProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 m.issuer,
+		"authorization_endpoint": m.srv.URL + "/authorize",
+		"token_endpoint":         m.srv.URL + "/token",
+		"jwks_uri":               m.srv.URL + "/jwks",
+	})
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (m *mockProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA", "kid": "rsa", "use": "sig",
+				"n": b64(m.rsaKey.N.Bytes()),
+				"e": b64(big.NewInt(int64(m.rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "EC", "kid": "ec", "use": "sig", "crv": "P-256",
+				"x": b64(m.ecKey.X.FillBytes(make([]byte, 32))),
+				"y": b64(m.ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+			// Skipped, the provider encrypts with it.
+			{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		},
+	})
+}
+
+// authorize is the login of the user at the provider, it returns the code
+// the provider redirects back with.
+func (m *mockProvider) authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("got code_challenge_method %q, want S256", q.Get("code_challenge_method"))
+	}
+
+	code = b64([]byte(q.Get("state") + "-code"))
+	m.mu.Lock()
+	m.logins[code] = mockLogin{nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+	m.mu.Unlock()
+	return code, q.Get("state")
+}
+
+func (m *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	fail := func(reason string) {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": reason})
+	}
+
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != testClientID || secret != testClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("redirect_uri") != testRedirectURL {
+		fail("bad request")
+		return
+	}
+
+	m.mu.Lock()
+	login, ok := m.logins[r.PostFormValue("code")]
+	delete(m.logins, r.PostFormValue("code"))
+	tamper, tamperToken := m.tamper, m.tamperToken
+	m.mu.Unlock()
+	if !ok {
+		fail("unknown code")
+		return
+	}
+	if Challenge(r.PostFormValue("code_verifier")) != login.challenge {
+		fail("pkce verification failed")
+		return
+	}
+
+	now := time.Now()
+	header := map[string]any{"alg": "RS256", "kid": "rsa", "typ": "JWT"}
+	claims := map[string]any{
+		"iss":            m.issuer,
+		"sub":            "user-1",
+		"aud":            testClientID,
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          login.nonce,
+		"email":          "ada@example.com",
+		"email_verified": true,
+		"name":           "Ada",
+	}
+	if tamper != nil {
+		tamper(header, claims)
+	}
+
+	token := m.sign(header, claims)
+	if tamperToken != nil {
+		token = tamperToken(token)
+	}
+	_ = json.NewEncoder(w).Encode(map[string]string{"id_token": token, "token_type": "Bearer"})
+}
+
+func (m *mockProvider) sign(header, claims map[string]any) string {
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	signed := b64(h) + "." + b64(c)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch header["alg"] {
+	case "RS256":
+		signature, _ = rsa.SignPKCS1v15(rand.Reader, m.rsaKey, crypto.SHA256, digest[:])
+	case "ES256":
+		r, s, _ := ecdsa.Sign(rand.Reader, m.ecKey, digest[:])
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + b64(signature)
+}
+
+// login runs the code flow up to the verified claims.
+func (m *mockProvider) login(t *testing.T, p *Provider) (Claims, error) {
+	t.Helper()
+	state, _ := RandomString()
+	nonce, _ := RandomString()
+	verifier, _ := RandomString()
+
+	authURL, err := p.AuthURL(context.Background(), state, nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthURL: %v", err)
+	}
+	code, _ := m.authorize(t, authURL)
+	return p.Exchange(context.Background(), code, verifier, nonce)
+}
+
+func TestAuthURL(t *testing.T) {
+	m := newMockProvider(t)
+
+	authURL, err := m.provider().AuthURL(context.Background(), "the-state", "the-nonce", "the-verifier")
+	if err != nil {
+		t.Fatalf("AuthURL: %v", err)
+	}
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != m.srv.URL+"/authorize" {
+		t.Errorf("got the endpoint %s, want the discovered one", got)
+	}
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          testRedirectURL,
+		"scope":                 "openid email profile",
+		"state":                 "the-state",
+		"nonce":                 "the-nonce",
+		"code_challenge":        Challenge("the-verifier"),
+		"code_challenge_method": "S256",
+	}
+	for key, value := range want {
+		if got := u.Query().Get(key); got != value {
+			t.Errorf("got %s=%q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestDiscoveryFailures(t *testing.T) {
+	t.Run("another issuer", func(t *testing.T) {
+		m := newMockProvider(t)
+		m.issuer = "https://evil.example.com"
+		if _, err := m.provider().AuthURL(context.Background(), "s", "n", "v"); err == nil {
+			t.Fatal("AuthURL succeeded with the discovery document of another issuer")
+		}
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		m := newMockProvider(t)
+		p := m.provider()
+		m.srv.Close()
+		if _, err := p.AuthURL(context.Background(), "s", "n", "v"); err == nil {
+			t.Fatal("AuthURL succeeded without a provider")
+		}
+	})
+
+	t.Run("incomplete", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]string{"issuer": "http://" + r.Host})
+		}))
+		defer srv.Close()
+		p := NewProvider(Config{Name: "mock", Issuer: srv.URL, ClientID: testClientID}, testRedirectURL, srv.Client())
+		if _, err := p.AuthURL(context.Background(), "s", "n", "v"); err == nil {
+			t.Fatal("AuthURL succeeded without the endpoints")
+		}
+	})
+}
+
+func TestExchange(t *testing.T) {
+	m := newMockProvider(t)
+	p := m.provider()
+
+	claims, err := m.login(t, p)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	want := Claims{Subject: "user-1", Email: "ada@example.com", EmailVerified: true, Name: "Ada"}
+	if claims != want {
+		t.Errorf("got %+v, want %+v", claims, want)
+	}
+
+	m.tamper = func(header, claims map[string]any) {
+		header["alg"], header["kid"] = "ES256", "ec"
+		// Some providers send it as a string.
+		claims["email_verified"] = "true"
+	}
+	claims, err = m.login(t, p)
+	if err != nil {
+		t.Fatalf("Exchange with ES256: %v", err)
+	}
+	if claims != want {
+		t.Errorf("got %+v with ES256, want %+v", claims, want)
+	}
+}
+
+func TestExchangeWrongVerifier(t *testing.T) {
+	m := newMockProvider(t)
+	p := m.provider()
+
+	authURL, err := p.AuthURL(context.Background(), "state", "nonce", "the-verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := m.authorize(t, authURL)
+
+	_, err = p.Exchange(context.Background(), code, "another-verifier", "nonce")
+	if err == nil || errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("got %v, want the token request to fail", err)
+	}
+	if !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("got %v, want the error of the provider", err)
+	}
+}
+
+func TestExchangeRejectsInvalidTokens(t *testing.T) {
+	tests := []struct {
+		name        string
+		tamper      func(header, claims map[string]any)
+		tamperToken func(token string) string
+	}{
+		{
+			name:   "wrong nonce",
+			tamper: func(_, claims map[string]any) { claims["nonce"] = "replayed" },
+		},
+		{
+			name: "bad signature",
+			tamperToken: func(token string) string {
+				parts := strings.Split(token, ".")
+				claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+				parts[1] = b64([]byte(strings.Replace(string(claims), "user-1", "admin", 1)))
+				return strings.Join(parts, ".")
+			},
+		},
+		{
+			name: "signed with another key",
+			tamper: func(header, _ map[string]any) {
+				// The EC key signs, the header names the RSA one.
+				header["alg"] = "ES256"
+			},
+		},
+		{
+			name:   "unsigned",
+			tamper: func(header, _ map[string]any) { header["alg"] = "none" },
+			tamperToken: func(token string) string {
+				return token[:strings.LastIndex(token, ".")+1]
+			},
+		},
+		{
+			name:   "unknown key",
+			tamper: func(header, _ map[string]any) { header["kid"] = "rotated" },
+		},
+		{
+			name:   "wrong issuer",
+			tamper: func(_, claims map[string]any) { claims["iss"] = "https://evil.example.com" },
+		},
+		{
+			name:   "wrong audience",
+			tamper: func(_, claims map[string]any) { claims["aud"] = "another-client" },
+		},
+		{
+			name:   "wrong authorized party",
+			tamper: func(_, claims map[string]any) { claims["aud"] = []string{testClientID, "another-client"} },
+		},
+		{
+			name:   "expired",
+			tamper: func(_, claims map[string]any) { claims["exp"] = time.Now().Add(-time.Hour).Unix() },
+		},
+		{
+			name:   "issued in the future",
+			tamper: func(_, claims map[string]any) { claims["iat"] = time.Now().Add(time.Hour).Unix() },
+		},
+		{
+			name:   "no subject",
+			tamper: func(_, claims map[string]any) { delete(claims, "sub") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockProvider(t)
+			m.tamper, m.tamperToken = tt.tamper, tt.tamperToken
+
+			if _, err := m.login(t, m.provider()); !errors.Is(err, ErrInvalidIDToken) {
+				t.Fatalf("got %v, want ErrInvalidIDToken", err)
+			}
+		})
+	}
+}
+
+func TestConfigValid(t *testing.T) {
+	valid := Config{Name: "keycloak", Issuer: "https://sso.example.com/realms/gobid", ClientID: "gobid"}
+	if err := valid.Valid(); err != nil {
+		t.Errorf("got %v for a valid config", err)
+	}
+
+	for name, c := range map[string]Config{
+		"uppercase name":    {Name: "Keycloak", Issuer: valid.Issuer, ClientID: "gobid"},
+		"name with a slash": {Name: "a/b", Issuer: valid.Issuer, ClientID: "gobid"},
+		"relative issuer":   {Name: "keycloak", Issuer: "/realms/gobid", ClientID: "gobid"},
+		"ftp issuer":        {Name: "keycloak", Issuer: "ftp://sso.example.com", ClientID: "gobid"},
+		"no client id":      {Name: "keycloak", Issuer: valid.Issuer},
+	} {
+		if err := c.Valid(); err == nil {
+			t.Errorf("%s: got no error", name)
+		}
+	}
+}
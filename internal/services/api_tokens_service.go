@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+)
+
+// The scopes an api token may be given, each one opens a set of endpoints.
+const (
+	ScopeBidsWrite     = "bids:write"
+	ScopeProductsWrite = "products:write"
+)
+
+const (
+	MaxAPITokensPerUser = 20
+
+	apiTokenPrefix = "gobid_"
+)
+
+var (
+	ErrAPITokenNotFound = errors.New("api token not found")
+	ErrTooManyAPITokens = errors.New("too many api tokens, revoke one first")
+	ErrInvalidAPIToken  = errors.New("the api token is invalid, revoked or expired")
+)
+
+// APITokenService manages the personal tokens the scripts of the users log in
+// with instead of a session.
+type APITokenService struct {
+	pool *pgxpool.Pool
+	db   *pgstore.Queries
+}
+
+func NewAPITokenService(pool *pgxpool.Pool) APITokenService {
+	return APITokenService{
+		pool: pool,
+		db:   pgstore.New(pool),
+	}
+}
+
+type APIToken struct {
+	ID     uuid.UUID `json:"id"`
+	Name   string    `json:"name"`
+	Scopes []string  `json:"scopes"`
+	// Only set when the token is created, it is not stored.
+	Token      string     `json:"token,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APITokenAuth is who a request with an api token is made by.
+type APITokenAuth struct {
+	TokenID uuid.UUID
+	UserID  uuid.UUID
+	Scopes  []string
+}
+
+func apiTokenFromRow(row pgstore.ApiToken) APIToken {
+	return APIToken{
+		ID:         row.ID,
+		Name:       row.Name,
+		Scopes:     row.Scopes,
+		ExpiresAt:  timePtr(row.ExpiresAt),
+		LastUsedAt: timePtr(row.LastUsedAt),
+		CreatedAt:  row.CreatedAt.Time,
+	}
+}
+
+// Create makes a new token, it is only returned this once. A nil expiresAt
+// makes a token that works until it is revoked.
+func (s *APITokenService) Create(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresAt *time.Time) (APIToken, error) {
+	count, err := s.db.CountApiTokensByUserId(ctx, userID)
+	if err != nil {
+		return APIToken{}, err
+	}
+	if count >= MaxAPITokensPerUser {
+		return APIToken{}, ErrTooManyAPITokens
+	}
+
+	// The prefix makes the leaked tokens easy to find, it is part of the hash.
+	random, _, err := newToken()
+	if err != nil {
+		return APIToken{}, err
+	}
+	token := apiTokenPrefix + random
+
+	expires := pgtype.Timestamptz{}
+	if expiresAt != nil {
+		expires = pgtype.Timestamptz{Time: *expiresAt, Valid: true}
+	}
+
+	row, err := s.db.CreateApiToken(ctx, pgstore.CreateApiTokenParams{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashToken(token),
+		Scopes:    scopes,
+		ExpiresAt: expires,
+	})
+	if err != nil {
+		return APIToken{}, err
+	}
+
+	created := apiTokenFromRow(row)
+	created.Token = token
+	return created, nil
+}
+
+func (s *APITokenService) List(ctx context.Context, userID uuid.UUID) ([]APIToken, error) {
+	rows, err := s.db.ListApiTokensByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]APIToken, 0, len(rows))
+	for _, row := range rows {
+		tokens = append(tokens, apiTokenFromRow(row))
+	}
+
+	return tokens, nil
+}
+
+func (s *APITokenService) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	deleted, err := s.db.DeleteApiToken(ctx, pgstore.DeleteApiTokenParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrAPITokenNotFound
+	}
+
+	return nil
+}
+
+// Authenticate finds the user of the token and records its use.
+func (s *APITokenService) Authenticate(ctx context.Context, token string) (APITokenAuth, error) {
+	row, err := s.db.UseApiToken(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return APITokenAuth{}, ErrInvalidAPIToken
+		}
+		return APITokenAuth{}, err
+	}
+
+	return APITokenAuth{
+		TokenID: row.ID,
+		UserID:  row.UserID,
+		Scopes:  row.Scopes,
+	}, nil
+}
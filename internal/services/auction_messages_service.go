@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/authz"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+// Kinds stored in the auction_messages table.
+const (
+	AuctionMessageChat   = "chat"
+	AuctionMessageAnswer = "answer"
+)
+
+const maxAuctionMessageChars = 280
+
+var (
+	ErrInvalidAuctionMessage = errors.New("message cannot be blank or longer than 280 characters")
+	ErrOnlySellerCanAnswer   = errors.New("only the seller can answer questions")
+	ErrInvalidReply          = errors.New("answers must reply to a question of this auction")
+	ErrMessageRejected       = errors.New("your message was rejected, links and email addresses are not allowed in the chat")
+)
+
+type AuctionMessagesService struct {
+	pool *pgxpool.Pool
+	// TODO: make this a interface in order to be more idiomatic
+	db *pgstore.Queries
+}
+
+func NewAuctionMessagesService(pool *pgxpool.Pool) AuctionMessagesService {
+	return AuctionMessagesService{
+		pool: pool,
+		db:   pgstore.New(pool),
+	}
+}
+
+type AuctionMessageData struct {
+	ID        uuid.UUID  `json:"id"`
+	ProductID uuid.UUID  `json:"product_id"`
+	SenderID  uuid.UUID  `json:"sender_id"`
+	Kind      string     `json:"kind"`
+	Body      string     `json:"body"`
+	ReplyTo   *uuid.UUID `json:"reply_to,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func newAuctionMessageData(m pgstore.AuctionMessage) AuctionMessageData {
+	data := AuctionMessageData{
+		ID:        m.ID,
+		ProductID: m.ProductID,
+		SenderID:  m.SenderID,
+		Kind:      m.Kind,
+		Body:      m.Body,
+		CreatedAt: m.CreatedAt.Time,
+	}
+	if m.ReplyTo.Valid {
+		replyTo := uuid.UUID(m.ReplyTo.Bytes)
+		data.ReplyTo = &replyTo
+	}
+	return data
+}
+
+var (
+	linkRX          = regexp.MustCompile(`(?i)(https?://|www\.)\S+`)
+	embeddedEmailRX = regexp.MustCompile(`[a-zA-Z0-9.+_-]+@[a-zA-Z0-9-]+\.[a-zA-Z0-9.-]+`)
+)
+
+// moderate flags messages trying to take the deal out of the platform.
+func moderate(body string) (bool, string) {
+	switch {
+	case linkRX.MatchString(body):
+		return true, "contains a link"
+	case embeddedEmailRX.MatchString(body):
+		return true, "contains an email address"
+	}
+	return false, ""
+}
+
+// PostChat stores a chat message from any user in the room. Flagged messages are
+// rejected with ErrMessageRejected, they are only kept as a record and never
+// shown.
+func (s *AuctionMessagesService) PostChat(ctx context.Context, productID, senderID uuid.UUID, body string) (AuctionMessageData, error) {
+	return s.post(ctx, pgstore.CreateAuctionMessageParams{
+		ProductID: productID,
+		SenderID:  senderID,
+		Kind:      AuctionMessageChat,
+		Body:      body,
+	})
+}
+
+// PostAnswer stores the seller answer to a question asked in the room.
+func (s *AuctionMessagesService) PostAnswer(ctx context.Context, productID, sellerID, replyTo uuid.UUID, body string) (AuctionMessageData, error) {
+	product, err := s.db.GetProductById(ctx, productID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return AuctionMessageData{}, ErrProductNotFound
+		}
+		return AuctionMessageData{}, err
+	}
+
+	actor, err := loadSubject(ctx, s.db, sellerID)
+	if err != nil {
+		return AuctionMessageData{}, err
+	}
+	if !authz.CanAnswerQuestions(actor, product.SellerID) {
+		return AuctionMessageData{}, ErrOnlySellerCanAnswer
+	}
+
+	question, err := s.db.GetAuctionMessageById(ctx, replyTo)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return AuctionMessageData{}, ErrInvalidReply
+		}
+		return AuctionMessageData{}, err
+	}
+
+	// A rejected question is hidden, an answer would show it again.
+	if question.ProductID != productID || question.Kind != AuctionMessageChat || question.IsFlagged {
+		return AuctionMessageData{}, ErrInvalidReply
+	}
+
+	return s.post(ctx, pgstore.CreateAuctionMessageParams{
+		ProductID: productID,
+		SenderID:  sellerID,
+		Kind:      AuctionMessageAnswer,
+		Body:      body,
+		ReplyTo:   pgtype.UUID{Bytes: replyTo, Valid: true},
+	})
+}
+
+func (s *AuctionMessagesService) post(ctx context.Context, args pgstore.CreateAuctionMessageParams) (AuctionMessageData, error) {
+	if !validator.NotBlank(args.Body) || !validator.MaxChars(args.Body, maxAuctionMessageChars) {
+		return AuctionMessageData{}, ErrInvalidAuctionMessage
+	}
+
+	flagged, reason := moderate(args.Body)
+	args.IsFlagged = flagged
+	args.FlagReason = pgtype.Text{String: reason, Valid: flagged}
+
+	message, err := s.db.CreateAuctionMessage(ctx, args)
+	if err != nil {
+		return AuctionMessageData{}, err
+	}
+
+	if message.IsFlagged {
+		return AuctionMessageData{}, ErrMessageRejected
+	}
+
+	return newAuctionMessageData(message), nil
+}
+
+// RecentMessages returns the last limit visible messages of the auction, oldest first.
+func (s *AuctionMessagesService) RecentMessages(ctx context.Context, productID uuid.UUID, limit int32) ([]AuctionMessageData, error) {
+	messages, err := s.db.ListRecentAuctionMessages(ctx, pgstore.ListRecentAuctionMessagesParams{
+		ProductID: productID,
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]AuctionMessageData, 0, len(messages))
+	for _, m := range messages {
+		data = append(data, newAuctionMessageData(m))
+	}
+	slices.Reverse(data)
+
+	return data, nil
+}
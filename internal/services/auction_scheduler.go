@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+const (
+	// Watchers are told this long before an auction ends.
+	EndingSoonWindow = 15 * time.Minute
+
+	settleBatchSize = 100
+)
+
+// AuctionScheduler runs the time based work of the auctions that cannot rely
+// on a room being open: the ending soon alerts, the settlement of ended
+// auctions and the saved search alerts for new listings.
+type AuctionScheduler struct {
+	ProductService     *ProductService
+	SavedSearchService *SavedSearchService
+	// How often the auctions are checked, it delays the alerts at most this much.
+	Interval time.Duration
+}
+
+// Run checks the auctions every Interval until ctx is done.
+func (s *AuctionScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.ProductService.NotifyEndingSoon(ctx, time.Now().Add(EndingSoonWindow)); err != nil {
+			slog.Error("Failed to send ending soon notifications", "error", err)
+		}
+		if err := s.settleEnded(ctx); err != nil {
+			slog.Error("Failed to settle ended auctions", "error", err)
+		}
+		if err := s.SavedSearchService.NotifyNewListings(ctx); err != nil {
+			slog.Error("Failed to match new listings with saved searches", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *AuctionScheduler) settleEnded(ctx context.Context) error {
+	ids, err := s.ProductService.UnsettledAuctions(ctx, settleBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if _, err := s.ProductService.SettleAuction(ctx, id); err != nil {
+			// Another instance got there first.
+			if errors.Is(err, ErrAuctionAlreadySettled) {
+				continue
+			}
+			return err
+		}
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log/slog"
 	"sync"
@@ -26,24 +27,149 @@ const (
 
 	// Internal
 	Disconnect
+
+	// Chat, used both as requests and as what is broadcast to the room
+	ChatMessage
+	SellerAnswer
+
+	// Responses
+	RoomSnapshot
+	FailedToSendMessage
+	Tick
+	AuctionCancelled
+)
+
+const (
+	// Bids and chat are limited separately so chatting never eats the bidding budget.
+	bidsPerSecond = 2
+	bidsBurst     = 5
+	chatPerSecond = 0.5
+	chatBurst     = 3
+
+	// How many chat messages a client gets when joining the room.
+	snapshotMessages = 50
+)
+
+// tickInterval tells how often the room broadcasts a Tick, the closer to the
+// end of the auction the more often clients resync their countdown.
+func tickInterval(remaining time.Duration) time.Duration {
+	switch {
+	case remaining > time.Hour:
+		return time.Minute
+	case remaining > 10*time.Minute:
+		return 15 * time.Second
+	case remaining > time.Minute:
+		return 5 * time.Second
+	default:
+		return time.Second
+	}
+}
+
+// Close codes sent on the websocket close frame when the room ends a connection.
+// RFC 6455 reserves the 4000-4999 range for application use.
+const (
+	CloseAuctionEnded       = 4000
+	CloseKicked             = 4001
+	CloseAuctionCancelled   = 4002
+	CloseAuctionRescheduled = 4003
+	CloseServerShutdown     = websocket.CloseGoingAway
+)
+
+var (
+	ErrAuctionEnded       = errors.New("auction has been finished")
+	ErrAuctionCancelled   = errors.New("auction has been cancelled")
+	ErrAuctionRescheduled = errors.New("auction has been rescheduled, reconnect to follow it")
+	ErrServerShutdown     = errors.New("server is shutting down")
+	ErrRoomClosed         = errors.New("auction room is closed")
 )
 
+// closeFrameFor maps the reason a room stopped to the close frame sent to its clients.
+func closeFrameFor(cause error) (int, string) {
+	switch {
+	case errors.Is(cause, ErrServerShutdown):
+		return CloseServerShutdown, ErrServerShutdown.Error()
+	case errors.Is(cause, ErrAuctionCancelled):
+		return CloseAuctionCancelled, ErrAuctionCancelled.Error()
+	case errors.Is(cause, ErrAuctionRescheduled):
+		return CloseAuctionRescheduled, ErrAuctionRescheduled.Error()
+	default:
+		return CloseAuctionEnded, ErrAuctionEnded.Error()
+	}
+}
+
 // Will hold all the auctionRooms
 type AuctionLobby struct {
 	Rooms map[uuid.UUID]*AuctionRoom
 	sync.Mutex
 }
 
+// Open adds the room to the lobby and runs it in a go routine,
+// removing it from the lobby once the room is done.
+func (l *AuctionLobby) Open(room *AuctionRoom) {
+	l.Lock()
+	l.Rooms[room.ID] = room
+	l.Unlock()
+
+	go func() {
+		room.Run()
+
+		l.Lock()
+		if l.Rooms[room.ID] == room {
+			delete(l.Rooms, room.ID)
+		}
+		l.Unlock()
+	}()
+}
+
+func (l *AuctionLobby) Room(id uuid.UUID) (*AuctionRoom, bool) {
+	l.Lock()
+	defer l.Unlock()
+	room, ok := l.Rooms[id]
+	return room, ok
+}
+
+// Shutdown closes every room with CloseServerShutdown and waits until their
+// clients received the close frame or ctx is done.
+func (l *AuctionLobby) Shutdown(ctx context.Context) error {
+	l.Lock()
+	rooms := make([]*AuctionRoom, 0, len(l.Rooms))
+	for _, room := range l.Rooms {
+		rooms = append(rooms, room)
+	}
+	l.Unlock()
+
+	for _, room := range rooms {
+		room.Close(ErrServerShutdown)
+	}
+
+	for _, room := range rooms {
+		if err := room.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type Message struct {
 	Message  string      `json:"message,omitempty"`
 	Kind     MessageKind `json:"kind"`
 	BidValue float64     `json:"bid_value,omitempty"`
 	UserID   uuid.UUID   `json:"user_id,omitempty"`
+	// Chat messages id, and the question a SellerAnswer replies to.
+	MessageID *uuid.UUID `json:"message_id,omitempty"`
+	ReplyTo   *uuid.UUID `json:"reply_to,omitempty"`
+	// Last chat messages, only sent on RoomSnapshot.
+	History []AuctionMessageData `json:"history,omitempty"`
+	// Set on every message sent by the room so clients can correct their clock skew.
+	ServerTime time.Time `json:"server_time"`
+	// Time left until the auction ends, set on every message too so the last
+	// ones say 0.
+	RemainingMs int64 `json:"remaining_ms"`
 }
 
 // A WS "chat" for a specific product.
 type AuctionRoom struct {
-	// Holds the deadline for the auction
+	// Holds the deadline for the auction, its cause tells why the room was closed.
 	Context context.Context
 	// Sync method for every message that needs to be Broadcast
 	Broadcast chan Message
@@ -51,43 +177,172 @@ type AuctionRoom struct {
 	Register   chan *Client
 	Unregister chan *Client
 
+	// Only accessed from the Run go routine.
 	Clients map[uuid.UUID]*Client
-
-	ProductService *ProductService
-	BidsService    *BidsService
-	ID             uuid.UUID
+	// The outcomes of the queries run off the Run go routine, applied by it.
+	results chan func()
+
+	ProductService  *ProductService
+	BidsService     *BidsService
+	MessagesService *AuctionMessagesService
+	ID              uuid.UUID
+
+	cancel context.CancelCauseFunc
+	stop   context.CancelFunc
+	// Closed once Run returns, after that nothing reads from the room channels.
+	done chan struct{}
+	// Clients connected when the room ended, written before done is closed.
+	leaving []*Client
 }
 
-func NewAuctionRoom(ctx context.Context, id uuid.UUID, productService *ProductService, bidsService *BidsService) *AuctionRoom {
+func NewAuctionRoom(
+	ctx context.Context,
+	id uuid.UUID,
+	auctionEnd time.Time,
+	productService *ProductService,
+	bidsService *BidsService,
+	messagesService *AuctionMessagesService,
+) *AuctionRoom {
+	ctx, cancel := context.WithCancelCause(ctx)
+	ctx, stop := context.WithDeadlineCause(ctx, auctionEnd, ErrAuctionEnded)
+
 	return &AuctionRoom{
-		ID:             id,
-		Broadcast:      make(chan Message),
-		Register:       make(chan *Client),
-		Unregister:     make(chan *Client),
-		Clients:        make(map[uuid.UUID]*Client),
-		Context:        ctx,
-		ProductService: productService,
-		BidsService:    bidsService,
+		ID:              id,
+		Broadcast:       make(chan Message),
+		Register:        make(chan *Client),
+		Unregister:      make(chan *Client),
+		Clients:         make(map[uuid.UUID]*Client),
+		results:         make(chan func()),
+		Context:         ctx,
+		ProductService:  productService,
+		BidsService:     bidsService,
+		MessagesService: messagesService,
+		cancel:          cancel,
+		stop:            stop,
+		done:            make(chan struct{}),
+	}
+}
+
+// Close stops the room, cause decides the close code sent to the clients.
+func (r *AuctionRoom) Close(cause error) {
+	r.cancel(cause)
+}
+
+func (r *AuctionRoom) Done() <-chan struct{} {
+	return r.done
+}
+
+// Wait blocks until the room is done and its clients connections were closed.
+func (r *AuctionRoom) Wait(ctx context.Context) error {
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	for _, client := range r.leaving {
+		select {
+		case <-client.writeDone:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Join registers the client in the room and starts its event loops.
+// If the room is already closed the connection is closed with the room close code.
+func (r *AuctionRoom) Join(client *Client) error {
+	select {
+	case r.Register <- client:
+	case <-r.done:
+		code, reason := closeFrameFor(context.Cause(r.Context))
+		client.writeClose(code, reason)
+		client.Conn.Close()
+		return ErrRoomClosed
+	}
+
+	go client.ReadEventLoop()
+	go client.WriteEventLoop()
+	return nil
+}
+
+// remaining is the time left until the auction deadline.
+func (r *AuctionRoom) remaining(now time.Time) time.Duration {
+	deadline, ok := r.Context.Deadline()
+	if !ok {
+		return 0
+	}
+	return max(deadline.Sub(now), 0)
+}
+
+// send never blocks the room, clients that can not keep up are kicked. The
+// clients that left are skipped, their Send channel is closed.
+func (r *AuctionRoom) send(client *Client, message Message) {
+	if current, ok := r.Clients[client.UserId]; !ok || current != client {
+		return
 	}
+
+	message.ServerTime = time.Now()
+	message.RemainingMs = r.remaining(message.ServerTime).Milliseconds()
+	select {
+	case client.Send <- message:
+	default:
+		slog.Info("Client too slow, kicking", "RoomId", r.ID, "user_id", client.UserId)
+		r.disconnect(client, CloseKicked, "too many pending messages")
+	}
+}
+
+// disconnect is the only place where a client Send channel is closed.
+func (r *AuctionRoom) disconnect(client *Client, code int, reason string) {
+	if current, ok := r.Clients[client.UserId]; !ok || current != client {
+		return
+	}
+
+	delete(r.Clients, client.UserId)
+	client.closeCode = code
+	client.closeReason = reason
+	close(client.Send)
+}
+
+// async runs query in its own go routine, so a slow query does not hold the
+// bids and the ticks of the whole room. The func it returns is run by the Run
+// go routine, the only one that may touch the clients.
+func (r *AuctionRoom) async(query func(ctx context.Context) func()) {
+	go func() {
+		apply := query(r.Context)
+		select {
+		case r.results <- apply:
+		case <-r.done:
+		}
+	}()
 }
 
 func (r *AuctionRoom) broadCastMessage(message Message) {
 	slog.Info("Message Recieved", "RoomId", r.ID, "message", message, "user_id", message.UserID)
 	switch message.Kind {
 	case PlaceBid:
+		if client, ok := r.Clients[message.UserID]; ok && !client.bidLimiter.Allow(time.Now()) {
+			r.send(client, Message{Kind: FailedToPlaceBid, Message: "too many bids, slow down", UserID: message.UserID})
+			return
+		}
+
 		bid, err := r.BidsService.PlaceBid(r.Context, r.ID, message.UserID, message.BidValue)
 		if err != nil {
-			if errors.Is(err, ErrBidIsTooLow) {
-				// Write back to the user that the bid is too low
-				if client, ok := r.Clients[message.UserID]; ok {
-					client.Send <- Message{Kind: FailedToPlaceBid, Message: ErrBidIsTooLow.Error(), UserID: message.UserID}
+			if client, ok := r.Clients[message.UserID]; ok {
+				if errors.Is(err, ErrBidIsTooLow) || errors.Is(err, ErrAuctionNotLive) || errors.Is(err, ErrEmailNotVerified) || errors.Is(err, ErrBidNotAllowed) {
+					// Write back to the user why the bid was refused
+					r.send(client, Message{Kind: FailedToPlaceBid, Message: err.Error(), UserID: message.UserID})
 					return
 				}
+				slog.Error("Failed to place bid", "RoomId", r.ID, "error", err)
+				r.send(client, Message{Kind: FailedToPlaceBid, Message: "failed to place bid, try again later", UserID: message.UserID})
 			}
+			return
 		}
 
 		if client, ok := r.Clients[message.UserID]; ok {
-			client.Send <- Message{Kind: SuccessfullyPlacedBid, Message: "Your bid was successfully placed."}
+			r.send(client, Message{Kind: SuccessfullyPlacedBid, Message: "Your bid was successfully placed."})
 		}
 
 		for id, client := range r.Clients {
@@ -95,44 +350,134 @@ func (r *AuctionRoom) broadCastMessage(message Message) {
 			if id == message.UserID { // Do not send this to the user.
 				continue
 			}
-			select {
-			case client.Send <- newBidMessage:
-			default:
-				close(client.Send)
-				delete(r.Clients, id)
-			}
+			r.send(client, newBidMessage)
 		}
+	case ChatMessage, SellerAnswer:
+		r.postChatMessage(message)
 	case InvalidJSON:
 		client, ok := r.Clients[message.UserID]
 		if !ok {
 			slog.Info("Client not found in hashmap")
 			return
 		}
-		client.Send <- message
+		r.send(client, message)
+	}
+}
+
+func (r *AuctionRoom) postChatMessage(message Message) {
+	client, ok := r.Clients[message.UserID]
+	if !ok {
+		slog.Info("Client not found in hashmap")
+		return
+	}
+
+	if !client.chatLimiter.Allow(time.Now()) {
+		r.send(client, Message{Kind: FailedToSendMessage, Message: "too many messages, slow down", UserID: message.UserID})
+		return
+	}
+
+	if message.Kind == SellerAnswer && message.ReplyTo == nil {
+		r.send(client, Message{Kind: FailedToSendMessage, Message: ErrInvalidReply.Error(), UserID: message.UserID})
+		return
+	}
+
+	r.async(func(ctx context.Context) func() {
+		var (
+			posted AuctionMessageData
+			err    error
+		)
+		if message.Kind == SellerAnswer {
+			posted, err = r.MessagesService.PostAnswer(ctx, r.ID, message.UserID, *message.ReplyTo, message.Message)
+		} else {
+			posted, err = r.MessagesService.PostChat(ctx, r.ID, message.UserID, message.Message)
+		}
+
+		return func() {
+			if err != nil {
+				r.chatFailed(client, err)
+				return
+			}
+
+			for _, c := range r.Clients {
+				r.send(c, Message{
+					Kind:      message.Kind,
+					Message:   posted.Body,
+					UserID:    posted.SenderID,
+					MessageID: &posted.ID,
+					ReplyTo:   posted.ReplyTo,
+				})
+			}
+		}
+	})
+}
+
+// chatFailed tells the client why its message was not posted.
+func (r *AuctionRoom) chatFailed(client *Client, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidAuctionMessage),
+		errors.Is(err, ErrOnlySellerCanAnswer),
+		errors.Is(err, ErrInvalidReply),
+		errors.Is(err, ErrMessageRejected):
+		r.send(client, Message{Kind: FailedToSendMessage, Message: err.Error(), UserID: client.UserId})
+	default:
+		slog.Error("Failed to post chat message", "RoomId", r.ID, "error", err)
+		r.send(client, Message{Kind: FailedToSendMessage, Message: "failed to send message, try again later", UserID: client.UserId})
+	}
+}
+
+// sendSnapshot brings a client that just joined up to date with the room chat.
+func (r *AuctionRoom) sendSnapshot(client *Client) {
+	r.async(func(ctx context.Context) func() {
+		history, err := r.MessagesService.RecentMessages(ctx, r.ID, snapshotMessages)
+
+		return func() {
+			// Without the chat the client still needs the time left.
+			snapshot := Message{Kind: RoomSnapshot, History: history}
+			if err != nil {
+				slog.Error("Failed to load room snapshot", "RoomId", r.ID, "error", err)
+				snapshot.Message = "failed to load the chat history"
+			}
+			r.send(client, snapshot)
+		}
+	})
+}
+
+func (r *AuctionRoom) broadcastTick() {
+	for _, client := range r.Clients {
+		r.send(client, Message{Kind: Tick})
 	}
 }
 
 func (r *AuctionRoom) unregisterClient(client *Client) {
 	slog.Info("New user disconnected", "userID", client.UserId)
-	delete(r.Clients, client.UserId)
-	close(client.Send)
+	r.disconnect(client, websocket.CloseNormalClosure, "")
 }
 
 func (r *AuctionRoom) registerClient(client *Client) {
 	slog.Info("New user connected", "client", client)
+	// The same user connecting twice replaces the older connection.
+	if old, ok := r.Clients[client.UserId]; ok {
+		r.disconnect(old, CloseKicked, "connected from another session")
+	}
 	r.Clients[client.UserId] = client
+	r.sendSnapshot(client)
 }
 
 // Should run in a go routine
 func (r *AuctionRoom) Run() {
+	tick := time.NewTimer(tickInterval(r.remaining(time.Now())))
 	defer func() {
-		close(r.Broadcast)
-		close(r.Register)
-		close(r.Unregister)
+		tick.Stop()
+		r.stop()
+		close(r.done)
 	}()
 
 	for {
 		select {
+		case <-tick.C:
+			r.broadcastTick()
+			tick.Reset(tickInterval(r.remaining(time.Now())))
+
 		case client := <-r.Register:
 			r.registerClient(client)
 
@@ -142,10 +487,23 @@ func (r *AuctionRoom) Run() {
 		case message := <-r.Broadcast:
 			r.broadCastMessage(message)
 
+		case apply := <-r.results:
+			apply()
+
 		case <-r.Context.Done():
-			slog.Info("Auction ending", "auctionID", r.ID)
+			cause := context.Cause(r.Context)
+			slog.Info("Auction ending", "auctionID", r.ID, "cause", cause)
+
+			code, reason := closeFrameFor(cause)
 			for _, client := range r.Clients {
-				client.Send <- Message{Kind: AuctionFinshed, Message: "auction has been finished"}
+				r.leaving = append(r.leaving, client)
+				switch code {
+				case CloseAuctionEnded:
+					r.send(client, Message{Kind: AuctionFinshed, Message: "auction has been finished"})
+				case CloseAuctionCancelled:
+					r.send(client, Message{Kind: AuctionCancelled, Message: ErrAuctionCancelled.Error()})
+				}
+				r.disconnect(client, code, reason)
 			}
 			return
 		}
@@ -160,7 +518,10 @@ const (
 
 	pingPeriod = (pongWait * 9) / 10
 
-	maxMessageSize = 512
+	// How long to wait for the peer to answer our close frame before dropping the conn.
+	closeGracePeriod = time.Second
+
+	maxMessageSize = 2048
 )
 
 type Client struct {
@@ -168,53 +529,74 @@ type Client struct {
 	Conn   *websocket.Conn
 	Send   chan Message
 	UserId uuid.UUID
+
+	// Only used by the room go routine.
+	bidLimiter  *tokenBucket
+	chatLimiter *tokenBucket
+
+	// Set by the room right before Send is closed.
+	closeCode   int
+	closeReason string
+	// Closed when ReadEventLoop and WriteEventLoop return.
+	readDone  chan struct{}
+	writeDone chan struct{}
 }
 
 func NewClient(room *AuctionRoom, conn *websocket.Conn, userId uuid.UUID) *Client {
 	return &Client{
-		Room:   room,
-		Conn:   conn,
-		Send:   make(chan Message, 512),
-		UserId: userId,
+		Room:        room,
+		Conn:        conn,
+		Send:        make(chan Message, 512),
+		UserId:      userId,
+		bidLimiter:  newTokenBucket(bidsPerSecond, bidsBurst),
+		chatLimiter: newTokenBucket(chatPerSecond, chatBurst),
+		readDone:    make(chan struct{}),
+		writeDone:   make(chan struct{}),
 	}
 }
 
+func (c *Client) writeClose(code int, reason string) error {
+	return c.Conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(writeWait),
+	)
+}
+
+// WriteEventLoop owns the connection: it is the only one writing to it and closing it.
 func (c *Client) WriteEventLoop() {
 	ticker := time.NewTicker(pingPeriod)
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
+		close(c.writeDone)
 	}()
 
 	// WriteEventLoop
 	for {
 		select {
 		case message, ok := <-c.Send:
-			if message.Kind == AuctionFinshed {
-				close(c.Send)
+			if !ok {
+				// The room closed Send, say goodbye with a proper close frame and
+				// give the peer a chance to answer it before dropping the conn.
+				if err := c.writeClose(c.closeCode, c.closeReason); err != nil {
+					return
+				}
+				select {
+				case <-c.readDone:
+				case <-time.After(closeGracePeriod):
+				}
 				return
 			}
 
 			// NOTE: If a deadline is meet the underlying c.Conn is corrupt and all writes will return an error.
-			// NOTE: Should I really add this to the videos???
 			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				// Make this a valid type...
-				c.Conn.WriteJSON(Message{
-					Kind:    websocket.CloseMessage,
-					Message: "closing websocket conn",
-				})
-				return
-			}
-
-			err := c.Conn.WriteJSON(message)
-			if err != nil {
-				c.Room.Unregister <- c
+			if err := c.Conn.WriteJSON(message); err != nil {
+				// Closing the conn makes ReadEventLoop fail and unregister the client.
 				return
 			}
 
 		case <-ticker.C:
-			// NOTE: Should I really add this to the videos???
 			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
@@ -227,11 +609,13 @@ func (c *Client) WriteEventLoop() {
 // one per conn
 func (c *Client) ReadEventLoop() {
 	defer func() {
-		c.Room.Unregister <- c
-		c.Conn.Close()
+		close(c.readDone)
+		select {
+		case c.Room.Unregister <- c:
+		case <-c.Room.done:
+		}
 	}()
 
-	// NOTE: Maybe remove those deadline stuff...
 	c.Conn.SetReadLimit(maxMessageSize)
 	c.Conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.Conn.SetPongHandler(func(string) error {
@@ -240,22 +624,28 @@ func (c *Client) ReadEventLoop() {
 	})
 
 	for {
-		var m Message
-		// NOTE: inform the user that sent this message to the room
-		m.UserID = c.UserId
-		err := c.Conn.ReadJSON(&m)
+		_, data, err := c.Conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, CloseAuctionEnded, CloseKicked, CloseAuctionCancelled, CloseAuctionRescheduled) {
 				slog.Error("Unexpected Close Error", "error", err)
-				return
 			}
+			return
+		}
 
-			c.Room.Broadcast <- Message{
+		var m Message
+		if err := json.Unmarshal(data, &m); err != nil {
+			m = Message{
 				Kind:    InvalidJSON,
 				Message: "invalid json",
-				UserID:  m.UserID,
 			}
 		}
-		c.Room.Broadcast <- m
+		// NOTE: inform the user that sent this message to the room
+		m.UserID = c.UserId
+
+		select {
+		case c.Room.Broadcast <- m:
+		case <-c.Room.done:
+			return
+		}
 	}
 }
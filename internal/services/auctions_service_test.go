@@ -0,0 +1,385 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+)
+
+var errNoDatabase = errors.New("no database in the tests")
+
+// noDatabase fails every query, the rooms must keep working without their
+// chat history.
+type noDatabase struct{}
+
+func (noDatabase) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errNoDatabase
+}
+
+func (noDatabase) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return nil, errNoDatabase
+}
+
+func (noDatabase) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	return noRow{}
+}
+
+type noRow struct{}
+
+func (noRow) Scan(...any) error {
+	return errNoDatabase
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func newTestRoom(t *testing.T, auctionEnd time.Time) *AuctionRoom {
+	t.Helper()
+	db := pgstore.New(noDatabase{})
+	return NewAuctionRoom(
+		context.Background(),
+		uuid.New(),
+		auctionEnd,
+		&ProductService{db: db},
+		&BidsService{db: db},
+		&AuctionMessagesService{db: db},
+	)
+}
+
+// serveRooms upgrades the requests and joins them to the room with the id
+// of the room query parameter, as the user of the user one.
+func serveRooms(t *testing.T, lobby *AuctionLobby) *httptest.Server {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		room, ok := lobby.Room(uuid.MustParse(r.URL.Query().Get("room")))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		_ = room.Join(NewClient(room, conn, uuid.MustParse(r.URL.Query().Get("user"))))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// dial connects the user to the room and waits for its snapshot, the user is
+// in the room once it is received.
+func dial(t *testing.T, srv *httptest.Server, room *AuctionRoom, userID uuid.UUID) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "?room=" + room.ID.String() + "&user=" + userID.String()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	var snapshot Message
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&snapshot); err != nil {
+		t.Fatalf("reading the snapshot: %v", err)
+	}
+	if snapshot.Kind != RoomSnapshot || snapshot.RemainingMs <= 0 {
+		t.Fatalf("got %+v, want a RoomSnapshot with the time left", snapshot)
+	}
+	return conn
+}
+
+// readUntilClose returns the messages read before the close frame and its
+// code, it fails when the connection ends without one.
+func readUntilClose(conn *websocket.Conn) ([]Message, int, error) {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var messages []Message
+	for {
+		var m Message
+		err := conn.ReadJSON(&m)
+		if err == nil {
+			messages = append(messages, m)
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) {
+			return messages, 0, fmt.Errorf("got %w, want a close frame", err)
+		}
+		return messages, closeErr.Code, nil
+	}
+}
+
+func TestAuctionRoomCloseCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		close    func(room *AuctionRoom)
+		wantCode int
+		// The kind of the last message before the close frame, if any.
+		wantLast *MessageKind
+	}{
+		{
+			name:     "ended",
+			close:    func(room *AuctionRoom) {}, // The deadline of the room.
+			wantCode: CloseAuctionEnded,
+			wantLast: ptr(AuctionFinshed),
+		},
+		{
+			name:     "cancelled",
+			close:    func(room *AuctionRoom) { room.Close(ErrAuctionCancelled) },
+			wantCode: CloseAuctionCancelled,
+			wantLast: ptr(AuctionCancelled),
+		},
+		{
+			name:     "rescheduled",
+			close:    func(room *AuctionRoom) { room.Close(ErrAuctionRescheduled) },
+			wantCode: CloseAuctionRescheduled,
+		},
+		{
+			name:     "server shutdown",
+			close:    func(room *AuctionRoom) { room.Close(ErrServerShutdown) },
+			wantCode: websocket.CloseGoingAway,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lobby := &AuctionLobby{Rooms: make(map[uuid.UUID]*AuctionRoom)}
+			srv := serveRooms(t, lobby)
+
+			end := time.Now().Add(time.Minute)
+			if tt.wantCode == CloseAuctionEnded {
+				end = time.Now().Add(500 * time.Millisecond)
+			}
+			room := newTestRoom(t, end)
+			lobby.Open(room)
+
+			conn := dial(t, srv, room, uuid.New())
+			tt.close(room)
+
+			messages, code, err := readUntilClose(conn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if code != tt.wantCode {
+				t.Errorf("got close code %d, want %d", code, tt.wantCode)
+			}
+			if tt.wantLast != nil {
+				if len(messages) == 0 || messages[len(messages)-1].Kind != *tt.wantLast {
+					t.Fatalf("got %+v before the close frame, want a last message of kind %d", messages, *tt.wantLast)
+				}
+				if remaining := messages[len(messages)-1].RemainingMs; tt.wantCode == CloseAuctionEnded && remaining != 0 {
+					t.Errorf("got %dms left in the last message, want 0", remaining)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := room.Wait(ctx); err != nil {
+				t.Fatalf("Wait: %v", err)
+			}
+		})
+	}
+}
+
+func TestAuctionRoomJoinClosedRoom(t *testing.T) {
+	lobby := &AuctionLobby{Rooms: make(map[uuid.UUID]*AuctionRoom)}
+	srv := serveRooms(t, lobby)
+
+	room := newTestRoom(t, time.Now().Add(time.Minute))
+	// Kept in the lobby, as a handler may still hold it once it stopped.
+	lobby.Rooms[room.ID] = room
+	go room.Run()
+	room.Close(ErrAuctionCancelled)
+	<-room.Done()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "?room=" + room.ID.String() + "&user=" + uuid.NewString()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	_, code, err := readUntilClose(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != CloseAuctionCancelled {
+		t.Errorf("got close code %d, want %d", code, CloseAuctionCancelled)
+	}
+}
+
+func TestAuctionRoomKicksTheOlderConnection(t *testing.T) {
+	lobby := &AuctionLobby{Rooms: make(map[uuid.UUID]*AuctionRoom)}
+	srv := serveRooms(t, lobby)
+	room := newTestRoom(t, time.Now().Add(time.Minute))
+	lobby.Open(room)
+	defer room.Close(ErrServerShutdown)
+
+	userID := uuid.New()
+	older := dial(t, srv, room, userID)
+	newer := dial(t, srv, room, userID)
+
+	_, code, err := readUntilClose(older)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != CloseKicked {
+		t.Errorf("got close code %d for the older connection, want %d", code, CloseKicked)
+	}
+
+	// The newer one is still in the room.
+	if err := newer.WriteJSON(Message{Kind: ChatMessage, Message: "hello"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	var m Message
+	newer.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := newer.ReadJSON(&m); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if m.Kind != FailedToSendMessage {
+		t.Errorf("got %+v, want the failure of the chat message without a database", m)
+	}
+}
+
+// The room closes the Send channel of a client once, however many reasons it
+// has to let the client go.
+func TestAuctionRoomDisconnectClosesSendOnce(t *testing.T) {
+	room := newTestRoom(t, time.Now().Add(time.Minute))
+	client := &Client{Room: room, Send: make(chan Message, 1), UserId: uuid.New()}
+	room.Clients[client.UserId] = client
+
+	room.disconnect(client, CloseKicked, "too many pending messages")
+	room.disconnect(client, websocket.CloseNormalClosure, "")
+	room.unregisterClient(client)
+
+	if client.closeCode != CloseKicked {
+		t.Errorf("got close code %d, want the first one %d", client.closeCode, CloseKicked)
+	}
+	if _, ok := <-client.Send; ok {
+		t.Error("Send is still open")
+	}
+
+	// A slow client is kicked, not blocked on nor sent to once gone.
+	slow := &Client{Room: room, Send: make(chan Message, 1), UserId: uuid.New()}
+	room.Clients[slow.UserId] = slow
+	room.send(slow, Message{Kind: Tick})
+	room.send(slow, Message{Kind: Tick})
+	room.send(slow, Message{Kind: Tick})
+	if _, ok := room.Clients[slow.UserId]; ok {
+		t.Error("the slow client is still in the room")
+	}
+	if slow.closeCode != CloseKicked {
+		t.Errorf("got close code %d for the slow client, want %d", slow.closeCode, CloseKicked)
+	}
+}
+
+// Clients leaving while the room closes race for Send, run with -race.
+func TestAuctionRoomConcurrentLeaveAndClose(t *testing.T) {
+	lobby := &AuctionLobby{Rooms: make(map[uuid.UUID]*AuctionRoom)}
+	srv := serveRooms(t, lobby)
+	room := newTestRoom(t, time.Now().Add(time.Minute))
+	lobby.Open(room)
+
+	conns := make([]*websocket.Conn, 20)
+	for i := range conns {
+		conns[i] = dial(t, srv, room, uuid.New())
+	}
+
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i%2 == 0 {
+				// Leaves on its own, with or without saying goodbye.
+				if i%4 == 0 {
+					_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				}
+				conn.Close()
+				return
+			}
+			_ = conn.WriteJSON(Message{Kind: PlaceBid, BidValue: 10})
+			_ = conn.WriteJSON(Message{Kind: ChatMessage, Message: "bye"})
+		}()
+	}
+	room.Close(ErrAuctionCancelled)
+	wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := room.Wait(ctx); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+}
+
+func TestAuctionLobbyShutdownDrainsRooms(t *testing.T) {
+	lobby := &AuctionLobby{Rooms: make(map[uuid.UUID]*AuctionRoom)}
+	srv := serveRooms(t, lobby)
+
+	var conns []*websocket.Conn
+	for range 3 {
+		room := newTestRoom(t, time.Now().Add(time.Hour))
+		lobby.Open(room)
+		for range 2 {
+			conns = append(conns, dial(t, srv, room, uuid.New()))
+		}
+	}
+
+	type closed struct {
+		code int
+		err  error
+	}
+	results := make(chan closed, len(conns))
+	for _, conn := range conns {
+		go func() {
+			// Answers the close frame, as the browsers do.
+			_, code, err := readUntilClose(conn)
+			results <- closed{code, err}
+		}()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := lobby.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	for range conns {
+		res := <-results
+		if res.err != nil {
+			t.Error(res.err)
+		} else if res.code != websocket.CloseGoingAway {
+			t.Errorf("got close code %d, want %d", res.code, websocket.CloseGoingAway)
+		}
+	}
+
+	// The rooms leave the lobby once they stopped.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		lobby.Lock()
+		left := len(lobby.Rooms)
+		lobby.Unlock()
+		if left == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d rooms are still in the lobby", left)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/lohanguedes/gobid/internal/authz"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+)
+
+// loadSubject reads the role of the user, for the policies of authz. It is
+// read again for every action so a demoted user loses the permissions at once.
+func loadSubject(ctx context.Context, db *pgstore.Queries, userID uuid.UUID) (authz.Subject, error) {
+	role, err := db.GetUserRole(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return authz.Subject{}, ErrUserNotFound
+		}
+		return authz.Subject{}, err
+	}
+
+	return authz.Subject{UserID: userID, Role: authz.Role(role)}, nil
+}
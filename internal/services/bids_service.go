@@ -3,10 +3,13 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/authz"
 	"github.com/lohanguedes/gobid/internal/store/pgstore"
 )
 
@@ -28,9 +31,16 @@ func NewBidsService(pool *pgxpool.Pool) BidsService {
 	}
 }
 
-var ErrBidIsTooLow = errors.New("the bid value is too low or a higher bid was already placed")
+var (
+	ErrBidIsTooLow    = errors.New("the bid value is too low or a higher bid was already placed")
+	ErrAuctionNotLive = errors.New("the auction is not accepting bids")
+	ErrBidNotAllowed  = errors.New("you may not bid on this auction")
+)
 
-func (s BidsService) PlaceBid(ctx context.Context, product_id, bidder_id uuid.UUID, amount float64) (pgstore.Bid, error) {
+// PlaceBid places the bid and adds the auction to the watchlist of the
+// bidder, the previous highest bidder is told they were outbid and the
+// webhooks of the seller get a bid.placed event.
+func (s BidsService) PlaceBid(ctx context.Context, product_id, bidder_id uuid.UUID, amount float64) (bid pgstore.Bid, err error) {
 	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return pgstore.Bid{}, err
@@ -47,23 +57,45 @@ func (s BidsService) PlaceBid(ctx context.Context, product_id, bidder_id uuid.UU
 
 	// Use qtx (queriesTx) instead
 	qtx := s.db.WithTx(tx)
-	product, err := qtx.GetProductById(ctx, product_id)
+	// Unverified users can follow the auction but not bid.
+	if err = checkEmailVerified(ctx, qtx, bidder_id); err != nil {
+		return pgstore.Bid{}, err
+	}
+
+	// Locking the product serializes concurrent bids and seller changes to the auction.
+	product, err := getProductForUpdate(ctx, qtx, product_id)
 	if err != nil {
 		return pgstore.Bid{}, err
 	}
 
+	bidder, err := loadSubject(ctx, qtx, bidder_id)
+	if err != nil {
+		return pgstore.Bid{}, err
+	}
+	if !authz.CanBid(bidder, product.SellerID) {
+		err = ErrBidNotAllowed
+		return pgstore.Bid{}, err
+	}
+
+	if auctionStatus(product, time.Now()) != AuctionStatusLive {
+		err = ErrAuctionNotLive
+		return pgstore.Bid{}, err
+	}
+
 	highestBid, err := qtx.GetHighestBidByProductId(ctx, product_id)
 	if err != nil {
 		if !errors.Is(err, pgx.ErrNoRows) {
 			return pgstore.Bid{}, err
 		}
+		err = nil
 	}
 
 	if product.BasePrice >= amount || highestBid.BidAmount >= amount {
-		return pgstore.Bid{}, ErrBidIsTooLow
+		err = ErrBidIsTooLow
+		return pgstore.Bid{}, err
 	}
 
-	highestBid, err = qtx.CreateBid(ctx, pgstore.CreateBidParams{
+	bid, err = qtx.CreateBid(ctx, pgstore.CreateBidParams{
 		ProductID: product_id,
 		BidderID:  bidder_id,
 		BidAmount: amount,
@@ -72,5 +104,53 @@ func (s BidsService) PlaceBid(ctx context.Context, product_id, bidder_id uuid.UU
 		return pgstore.Bid{}, err
 	}
 
-	return highestBid, err
+	// Bidders follow the auctions they bid on, they can unwatch them later.
+	err = qtx.AddToWatchlist(ctx, pgstore.AddToWatchlistParams{
+		UserID:    bidder_id,
+		ProductID: product_id,
+	})
+	if err != nil {
+		return pgstore.Bid{}, err
+	}
+
+	err = enqueueWebhookEvent(ctx, qtx, product.SellerID, WebhookBidPlaced, BidPlacedEvent{
+		ProductID:   product.ID,
+		ProductName: product.ProductName,
+		BidID:       bid.ID,
+		BidderID:    bid.BidderID,
+		Amount:      bid.BidAmount,
+		PlacedAt:    bid.CreatedAt.Time,
+	})
+	if err != nil {
+		return pgstore.Bid{}, err
+	}
+
+	if highestBid.BidderID != uuid.Nil && highestBid.BidderID != bidder_id {
+		err = notifyOutbid(ctx, qtx, product, highestBid.BidderID, amount)
+		if err != nil {
+			return pgstore.Bid{}, err
+		}
+	}
+
+	return bid, err
+}
+
+// notifyOutbid tells the previous highest bidder about the new bid, unless
+// they stopped watching the auction.
+func notifyOutbid(ctx context.Context, qtx *pgstore.Queries, product ProductData, userID uuid.UUID, amount float64) error {
+	watching, err := qtx.IsWatchingProduct(ctx, pgstore.IsWatchingProductParams{
+		UserID:    userID,
+		ProductID: product.ID,
+	})
+	if err != nil || !watching {
+		return err
+	}
+
+	return enqueueNotifications(ctx, qtx, Notification{
+		UserID:    userID,
+		Kind:      NotificationOutbid,
+		ProductID: &product.ID,
+		Title:     "You were outbid",
+		Body:      fmt.Sprintf("Someone bid %.2f on %s.", amount, product.ProductName),
+	})
 }
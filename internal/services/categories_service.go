@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+var (
+	ErrCategoryNotFound      = errors.New("category not found")
+	ErrDuplicateCategorySlug = errors.New("a category with this slug already exists")
+	// Returned when a category would become its own ancestor.
+	ErrCategoryCycle = errors.New("a category cannot be moved under itself or one of its descendants")
+	// Categories with subcategories or products cannot be deleted.
+	ErrCategoryInUse = errors.New("category still has subcategories or products")
+)
+
+type CategoryService struct {
+	pool *pgxpool.Pool
+	db   *pgstore.Queries
+}
+
+func NewCategoryService(pool *pgxpool.Pool) CategoryService {
+	return CategoryService{
+		pool: pool,
+		db:   pgstore.New(pool),
+	}
+}
+
+type CategoryData struct {
+	ID       uuid.UUID  `json:"id"`
+	ParentID *uuid.UUID `json:"parent_id,omitempty"`
+	Name     string     `json:"name"`
+	Slug     string     `json:"slug"`
+	// Only the attributes defined by this category, see Schema for the
+	// ones its products must follow.
+	AttributeSchema validator.AttributeSchema `json:"attribute_schema"`
+	CreatedAt       time.Time                 `json:"created_at"`
+	UpdatedAt       time.Time                 `json:"updated_at"`
+}
+
+// CategoryNode is a category with its subcategories, as returned by Tree.
+type CategoryNode struct {
+	CategoryData
+	Children []*CategoryNode `json:"children"`
+}
+
+func categoryFromRow(row pgstore.Category) (CategoryData, error) {
+	schema := validator.AttributeSchema{}
+	if err := json.Unmarshal(row.AttributeSchema, &schema); err != nil {
+		return CategoryData{}, fmt.Errorf("decode category schema: %w", err)
+	}
+
+	return CategoryData{
+		ID:              row.ID,
+		ParentID:        uuidPtr(row.ParentID),
+		Name:            row.Name,
+		Slug:            row.Slug,
+		AttributeSchema: schema,
+		CreatedAt:       row.CreatedAt.Time,
+		UpdatedAt:       row.UpdatedAt.Time,
+	}, nil
+}
+
+func encodeSchema(schema validator.AttributeSchema) ([]byte, error) {
+	if schema == nil {
+		schema = validator.AttributeSchema{}
+	}
+	return json.Marshal(schema)
+}
+
+func categoryParent(parentID *uuid.UUID) pgtype.UUID {
+	if parentID == nil {
+		return pgtype.UUID{}
+	}
+	return pgtype.UUID{Bytes: *parentID, Valid: true}
+}
+
+// categoryError maps the constraint violations of the categories table,
+// fkErr is what a foreign key violation means for the statement that failed.
+func categoryError(err, fkErr error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return ErrDuplicateCategorySlug
+		case "23503":
+			return fkErr
+		}
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrCategoryNotFound
+	}
+	return err
+}
+
+func (s *CategoryService) CreateCategory(ctx context.Context, parentID *uuid.UUID, name, slug string, schema validator.AttributeSchema) (CategoryData, error) {
+	encodedSchema, err := encodeSchema(schema)
+	if err != nil {
+		return CategoryData{}, err
+	}
+
+	row, err := s.db.CreateCategory(ctx, pgstore.CreateCategoryParams{
+		ParentID:        categoryParent(parentID),
+		Name:            name,
+		Slug:            slug,
+		AttributeSchema: encodedSchema,
+	})
+	if err != nil {
+		// A missing parent is reported as a missing category.
+		return CategoryData{}, categoryError(err, ErrCategoryNotFound)
+	}
+
+	return categoryFromRow(row)
+}
+
+// UpdateCategory replaces the category. Products already listed keep their
+// attributes even if they no longer follow the new schema.
+func (s *CategoryService) UpdateCategory(ctx context.Context, id uuid.UUID, parentID *uuid.UUID, name, slug string, schema validator.AttributeSchema) (CategoryData, error) {
+	encodedSchema, err := encodeSchema(schema)
+	if err != nil {
+		return CategoryData{}, err
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return CategoryData{}, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := s.db.WithTx(tx)
+
+	if parentID != nil {
+		// The category itself is part of its own subtree.
+		cycle, err := qtx.IsCategoryDescendant(ctx, pgstore.IsCategoryDescendantParams{
+			AncestorID: id,
+			CategoryID: *parentID,
+		})
+		if err != nil {
+			return CategoryData{}, err
+		}
+		if cycle {
+			return CategoryData{}, ErrCategoryCycle
+		}
+	}
+
+	row, err := qtx.UpdateCategory(ctx, pgstore.UpdateCategoryParams{
+		ID:              id,
+		ParentID:        categoryParent(parentID),
+		Name:            name,
+		Slug:            slug,
+		AttributeSchema: encodedSchema,
+	})
+	if err != nil {
+		return CategoryData{}, categoryError(err, ErrCategoryNotFound)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return CategoryData{}, err
+	}
+
+	return categoryFromRow(row)
+}
+
+func (s *CategoryService) DeleteCategory(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.db.GetCategoryById(ctx, id); err != nil {
+		return categoryError(err, ErrCategoryNotFound)
+	}
+
+	if err := s.db.DeleteCategory(ctx, id); err != nil {
+		return categoryError(err, ErrCategoryInUse)
+	}
+
+	return nil
+}
+
+// Tree returns the root categories with their subcategories nested, siblings sorted by name.
+func (s *CategoryService) Tree(ctx context.Context) ([]*CategoryNode, error) {
+	rows, err := s.db.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uuid.UUID]*CategoryNode, len(rows))
+	for _, row := range rows {
+		category, err := categoryFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+		nodes[row.ID] = &CategoryNode{CategoryData: category, Children: []*CategoryNode{}}
+	}
+
+	roots := []*CategoryNode{}
+	// rows are sorted by name, so appending keeps the siblings sorted.
+	for _, row := range rows {
+		node := nodes[row.ID]
+		if node.ParentID == nil {
+			roots = append(roots, node)
+			continue
+		}
+		parent, ok := nodes[*node.ParentID]
+		if !ok {
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+
+	return roots, nil
+}
+
+// Schema returns the attributes the products of the category must follow,
+// including the ones inherited from its ancestors.
+func (s *CategoryService) Schema(ctx context.Context, id uuid.UUID) (validator.AttributeSchema, error) {
+	return categorySchema(ctx, s.db, id)
+}
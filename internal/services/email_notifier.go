@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/mail"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+)
+
+// EmailNotifier emails the notifications that have a template, to the users
+// that did not turn that kind of email off. The other ones are skipped.
+type EmailNotifier struct {
+	db        *pgstore.Queries
+	mailer    mail.Mailer
+	templates *mail.Templates
+}
+
+func NewEmailNotifier(pool *pgxpool.Pool, mailer mail.Mailer, templates *mail.Templates) EmailNotifier {
+	return EmailNotifier{
+		db:        pgstore.New(pool),
+		mailer:    mailer,
+		templates: templates,
+	}
+}
+
+// emailData is what the mail templates are rendered with.
+type emailData struct {
+	UserName string
+	Title    string
+	Body     string
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, notifications ...Notification) error {
+	var errs []error
+	for _, notification := range notifications {
+		if err := n.send(ctx, notification); err != nil {
+			errs = append(errs, fmt.Errorf("email %s to %s: %w", notification.Kind, notification.UserID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (n *EmailNotifier) send(ctx context.Context, notification Notification) error {
+	pref, ok := emailPreferences[notification.Kind]
+	if !ok {
+		return nil
+	}
+
+	recipient, err := n.db.GetEmailRecipient(ctx, pgstore.GetEmailRecipientParams{
+		Kind:   pref,
+		UserID: notification.UserID,
+	})
+	if err != nil {
+		// The account is gone.
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if !recipient.SendEmail {
+		return nil
+	}
+
+	msg, err := n.templates.Render(notification.Kind, emailData{
+		UserName: recipient.UserName,
+		Title:    notification.Title,
+		Body:     notification.Body,
+	})
+	if err != nil {
+		return err
+	}
+	msg.To = recipient.Email
+
+	// The outbox may deliver an event again, its email is only sent once. It
+	// is claimed before sending so two deliveries at once cannot both send it.
+	claimed, err := n.db.ClaimSentEmail(ctx, notification.ID)
+	if err != nil {
+		return err
+	}
+	if claimed == 0 {
+		return nil
+	}
+	if err := n.mailer.Send(ctx, msg); err != nil {
+		// Let the retry send it.
+		return errors.Join(err, n.db.ReleaseSentEmail(context.WithoutCancel(ctx), notification.ID))
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/mail"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+)
+
+// How long an email verification link works.
+const EmailVerificationTTL = 48 * time.Hour
+
+var (
+	ErrEmailNotVerified         = errors.New("verify your email before bidding or selling")
+	ErrEmailAlreadyVerified     = errors.New("the email is already verified")
+	ErrInvalidVerificationToken = errors.New("the verification token is invalid, used or expired")
+)
+
+// EmailVerificationService emails the users a link proving they own their
+// email, until they open it they can watch auctions but not bid or sell.
+// Like the password reset links, they are sent right away and only the hash
+// of the token is stored.
+type EmailVerificationService struct {
+	pool      *pgxpool.Pool
+	db        *pgstore.Queries
+	mailer    mail.Mailer
+	templates *mail.Templates
+	// The page of the frontend that confirms the email, the token is added
+	// to its query.
+	verifyURL string
+}
+
+func NewEmailVerificationService(pool *pgxpool.Pool, mailer mail.Mailer, templates *mail.Templates, verifyURL string) EmailVerificationService {
+	return EmailVerificationService{
+		pool:      pool,
+		db:        pgstore.New(pool),
+		mailer:    mailer,
+		templates: templates,
+		verifyURL: verifyURL,
+	}
+}
+
+// verifyEmail is what the verify_email template is rendered with.
+type verifyEmail struct {
+	UserName string
+	Title    string
+	URL      string
+	ValidFor string
+}
+
+// Send emails a new verification link to the user, the older ones keep
+// working until they expire.
+func (s *EmailVerificationService) Send(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.db.GetUserById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if user.EmailVerifiedAt.Valid {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, hash, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	err = s.db.CreateEmailVerificationToken(ctx, pgstore.CreateEmailVerificationTokenParams{
+		TokenHash: hash,
+		UserID:    user.ID,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(EmailVerificationTTL), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	link, err := tokenURL(s.verifyURL, token)
+	if err != nil {
+		return err
+	}
+
+	msg, err := s.templates.Render("verify_email", verifyEmail{
+		UserName: user.UserName,
+		Title:    "Verify your email",
+		URL:      link,
+		ValidFor: "2 days",
+	})
+	if err != nil {
+		return err
+	}
+	msg.To = user.Email
+
+	return s.mailer.Send(ctx, msg)
+}
+
+// Verify marks the email of the user the token was sent to as verified and
+// returns their id.
+func (s *EmailVerificationService) Verify(ctx context.Context, token string) (uuid.UUID, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+	userID, err := qtx.UseEmailVerificationToken(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.UUID{}, ErrInvalidVerificationToken
+		}
+		return uuid.UUID{}, err
+	}
+
+	if err := qtx.MarkEmailVerified(ctx, userID); err != nil {
+		return uuid.UUID{}, err
+	}
+	if err := qtx.DeleteEmailVerificationTokensByUserId(ctx, userID); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	return userID, nil
+}
+
+// checkEmailVerified fails with ErrEmailNotVerified unless the user verified
+// their email.
+func checkEmailVerified(ctx context.Context, qtx *pgstore.Queries, userID uuid.UUID) error {
+	verified, err := qtx.IsEmailVerified(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if !verified {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+)
+
+// Login attempt outcomes.
+const (
+	LoginSucceeded = "succeeded"
+	LoginFailed    = "failed"
+	LoginLocked    = "locked"
+)
+
+const (
+	// The failures older than this are forgotten.
+	loginFailureWindow = 24 * time.Hour
+	// The ips are counted over a shorter window, they are shared by many users.
+	ipFailureWindow = 15 * time.Minute
+
+	// The failures allowed before the backoff starts.
+	freeEmailFailures = 5
+	freeIPFailures    = 20
+
+	// The first lockout, it doubles with every other failure.
+	loginLockoutBase = 30 * time.Second
+	maxLoginLockout  = time.Hour
+)
+
+// LoginLockedError is returned instead of checking the password when the
+// email or the ip failed too many times recently.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("too many failed logins, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+// dummyPasswordHash is compared with the password of the unknown emails, so
+// they take as long as the wrong passwords.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := hashPassword("not the password of anyone")
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+// loginLockout is how long the logins stay locked after the last failure.
+func loginLockout(failures, free int64) time.Duration {
+	if failures < free {
+		return 0
+	}
+	lockout := loginLockoutBase
+	for i := free; i < failures && lockout < maxLoginLockout; i++ {
+		lockout *= 2
+	}
+	return min(lockout, maxLoginLockout)
+}
+
+// loginLockedFor returns how long the email and the ip must wait before
+// trying again, zero when they may try now.
+func loginLockedFor(ctx context.Context, db *pgstore.Queries, email, ip string, now time.Time) (time.Duration, error) {
+	byEmail, err := db.GetLoginFailuresByEmail(ctx, pgstore.GetLoginFailuresByEmailParams{
+		Email: email,
+		Since: pgtype.Timestamptz{Time: now.Add(-loginFailureWindow), Valid: true},
+	})
+	if err != nil {
+		return 0, err
+	}
+	byIP, err := db.GetLoginFailuresByIp(ctx, pgstore.GetLoginFailuresByIpParams{
+		Ip:    ip,
+		Since: pgtype.Timestamptz{Time: now.Add(-ipFailureWindow), Valid: true},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var wait time.Duration
+	if byEmail.LastFailure.Valid {
+		until := byEmail.LastFailure.Time.Add(loginLockout(byEmail.Failures, freeEmailFailures))
+		wait = max(wait, until.Sub(now))
+	}
+	if byIP.LastFailure.Valid {
+		until := byIP.LastFailure.Time.Add(loginLockout(byIP.Failures, freeIPFailures))
+		wait = max(wait, until.Sub(now))
+	}
+	return wait, nil
+}
+
+func recordLoginAttempt(ctx context.Context, db *pgstore.Queries, email, ip string, userID uuid.UUID, outcome string) error {
+	return db.CreateLoginAttempt(ctx, pgstore.CreateLoginAttemptParams{
+		Email:   email,
+		UserID:  pgtype.UUID{Bytes: userID, Valid: userID != uuid.Nil},
+		Ip:      ip,
+		Outcome: outcome,
+	})
+}
+
+// recordFirstFactor records the login of a user who proved who they are with
+// their password or an identity provider. With two factor authentication the
+// login only succeeds with the right code, a success now would reset the
+// failures and leave the codes to be guessed.
+func recordFirstFactor(ctx context.Context, db *pgstore.Queries, email, ip string, userID uuid.UUID) error {
+	user, err := db.GetUserTotp(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.TotpEnabledAt.Valid {
+		return nil
+	}
+	return recordLoginAttempt(ctx, db, email, ip, userID, LoginSucceeded)
+}
+
+type LoginAttempt struct {
+	ID        uuid.UUID  `json:"id"`
+	Email     string     `json:"email"`
+	UserID    *uuid.UUID `json:"user_id,omitempty"`
+	IP        string     `json:"ip"`
+	Outcome   string     `json:"outcome"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// LoginAttemptFilter narrows the audit of the logins, the empty fields match
+// every attempt.
+type LoginAttemptFilter struct {
+	Email   string
+	IP      string
+	Outcome string
+}
+
+func optionalText(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
+}
+
+// LoginAttempts returns the newest login attempts matching the filter.
+func (us *UserService) LoginAttempts(ctx context.Context, filter LoginAttemptFilter, limit int32) ([]LoginAttempt, error) {
+	rows, err := us.db.ListLoginAttempts(ctx, pgstore.ListLoginAttemptsParams{
+		Email:    optionalText(filter.Email),
+		Ip:       optionalText(filter.IP),
+		Outcome:  optionalText(filter.Outcome),
+		PageSize: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := make([]LoginAttempt, 0, len(rows))
+	for _, row := range rows {
+		attempts = append(attempts, LoginAttempt{
+			ID:        row.ID,
+			Email:     row.Email,
+			UserID:    uuidPtr(row.UserID),
+			IP:        row.Ip,
+			Outcome:   row.Outcome,
+			CreatedAt: row.CreatedAt.Time,
+		})
+	}
+
+	return attempts, nil
+}
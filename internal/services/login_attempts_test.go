@@ -0,0 +1,34 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLoginLockout(t *testing.T) {
+	tests := []struct {
+		failures, free int64
+		want           time.Duration
+	}{
+		{failures: 0, free: freeEmailFailures, want: 0},
+		{failures: freeEmailFailures - 1, free: freeEmailFailures, want: 0},
+		{failures: freeEmailFailures, free: freeEmailFailures, want: 30 * time.Second},
+		{failures: freeEmailFailures + 1, free: freeEmailFailures, want: time.Minute},
+		{failures: freeEmailFailures + 2, free: freeEmailFailures, want: 2 * time.Minute},
+		{failures: freeEmailFailures + 6, free: freeEmailFailures, want: 32 * time.Minute},
+		// 64 minutes is over the cap.
+		{failures: freeEmailFailures + 7, free: freeEmailFailures, want: maxLoginLockout},
+		{failures: freeEmailFailures + 1000, free: freeEmailFailures, want: maxLoginLockout},
+		{failures: math.MaxInt64, free: freeEmailFailures, want: maxLoginLockout},
+		{failures: freeIPFailures - 1, free: freeIPFailures, want: 0},
+		{failures: freeIPFailures, free: freeIPFailures, want: 30 * time.Second},
+		{failures: freeIPFailures + 3, free: freeIPFailures, want: 4 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := loginLockout(tt.failures, tt.free); got != tt.want {
+			t.Errorf("loginLockout(%d, %d) = %s, want %s", tt.failures, tt.free, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// How many notifications a stream may lag behind before it misses some.
+const notificationStreamBuffer = 16
+
+// NotificationHub pushes the stored notifications to the streams the users
+// have open, a user may have one per tab or device.
+type NotificationHub struct {
+	mu      sync.Mutex
+	streams map[uuid.UUID]map[chan NotificationData]struct{}
+	closed  bool
+}
+
+func NewNotificationHub() *NotificationHub {
+	return &NotificationHub{
+		streams: make(map[uuid.UUID]map[chan NotificationData]struct{}),
+	}
+}
+
+// Subscribe opens a stream for the user, the returned function closes it.
+// The channel is also closed when the hub is.
+func (h *NotificationHub) Subscribe(userID uuid.UUID) (<-chan NotificationData, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan NotificationData, notificationStreamBuffer)
+	if h.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	if h.streams[userID] == nil {
+		h.streams[userID] = make(map[chan NotificationData]struct{})
+	}
+	h.streams[userID][ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if _, ok := h.streams[userID][ch]; !ok {
+			return
+		}
+		delete(h.streams[userID], ch)
+		if len(h.streams[userID]) == 0 {
+			delete(h.streams, userID)
+		}
+		close(ch)
+	}
+}
+
+// Publish never blocks: a stream that is not keeping up misses the
+// notification, it is still in the inbox.
+func (h *NotificationHub) Publish(n NotificationData) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.streams[n.UserID] {
+		select {
+		case ch <- n:
+		default:
+		}
+	}
+}
+
+// Close ends every stream, the http.Server does not shut down while they are open.
+func (h *NotificationHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for userID, streams := range h.streams {
+		for ch := range streams {
+			close(ch)
+		}
+		delete(h.streams, userID)
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+)
+
+// The notifications that can be sent by email, each one is on unless the
+// user turns it off.
+const (
+	EmailPreferenceOutbid     = "outbid"
+	EmailPreferenceAuctionWon = "auction_won"
+	EmailPreferenceItemSold   = "item_sold"
+	EmailPreferenceAccount    = "account"
+)
+
+// emailPreferences maps the notification kinds sent by email to the
+// preference that controls them.
+var emailPreferences = map[string]string{
+	NotificationOutbid:          EmailPreferenceOutbid,
+	NotificationAuctionWon:      EmailPreferenceAuctionWon,
+	NotificationItemSold:        EmailPreferenceItemSold,
+	NotificationWelcome:         EmailPreferenceAccount,
+	NotificationPasswordChanged: EmailPreferenceAccount,
+}
+
+// EmailPreferences returns whether the user gets each kind of email.
+func (s *NotificationService) EmailPreferences(ctx context.Context, userID uuid.UUID) (map[string]bool, error) {
+	rows, err := s.db.ListNotificationPreferencesByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs := map[string]bool{
+		EmailPreferenceOutbid:     true,
+		EmailPreferenceAuctionWon: true,
+		EmailPreferenceItemSold:   true,
+		EmailPreferenceAccount:    true,
+	}
+	for _, row := range rows {
+		if _, ok := prefs[row.Kind]; ok {
+			prefs[row.Kind] = row.Email
+		}
+	}
+
+	return prefs, nil
+}
+
+// SetEmailPreferences changes the given preferences, the others are kept.
+func (s *NotificationService) SetEmailPreferences(ctx context.Context, userID uuid.UUID, prefs map[string]bool) (map[string]bool, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+	for kind, email := range prefs {
+		err := qtx.SetNotificationPreference(ctx, pgstore.SetNotificationPreferenceParams{
+			UserID: userID,
+			Kind:   kind,
+			Email:  email,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.EmailPreferences(ctx, userID)
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Notification kinds.
+const (
+	NotificationOutbid            = "outbid"
+	NotificationAuctionEndingSoon = "auction_ending_soon"
+	NotificationAuctionEnded      = "auction_ended"
+	NotificationAuctionWon        = "auction_won"
+	NotificationItemSold          = "item_sold"
+	NotificationAuctionCancelled  = "auction_cancelled"
+	NotificationSavedSearchMatch  = "saved_search_match"
+	NotificationWelcome           = "welcome"
+	NotificationPasswordChanged   = "password_changed"
+)
+
+// Notification is something a user must be told about even when they are
+// not in the auction room.
+type Notification struct {
+	// Set by the outbox, it is the same when the notification is delivered again.
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	Kind   string    `json:"kind"`
+	// Set when the notification is about an auction.
+	ProductID *uuid.UUID `json:"product_id,omitempty"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+}
+
+// Notifier delivers notifications to users, implementations must be safe
+// for concurrent use.
+type Notifier interface {
+	Notify(ctx context.Context, notifications ...Notification) error
+}
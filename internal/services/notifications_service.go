@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// notificationSort is only used to tell notification cursors apart from the product ones.
+const notificationSort = "notifications"
+
+// NotificationService keeps the inbox of the users, it is the Notifier of the
+// inbox outbox consumer.
+type NotificationService struct {
+	pool *pgxpool.Pool
+	db   *pgstore.Queries
+	hub  *NotificationHub
+}
+
+func NewNotificationService(pool *pgxpool.Pool, hub *NotificationHub) NotificationService {
+	return NotificationService{
+		pool: pool,
+		db:   pgstore.New(pool),
+		hub:  hub,
+	}
+}
+
+type NotificationData struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Kind      string     `json:"kind"`
+	ProductID *uuid.UUID `json:"product_id,omitempty"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func notificationFromRow(row pgstore.Notification) NotificationData {
+	return NotificationData{
+		ID:        row.ID,
+		UserID:    row.UserID,
+		Kind:      row.Kind,
+		ProductID: uuidPtr(row.ProductID),
+		Title:     row.Title,
+		Body:      row.Body,
+		ReadAt:    timePtr(row.ReadAt),
+		CreatedAt: row.CreatedAt.Time,
+	}
+}
+
+// Notify stores the notifications in the inbox of the users and pushes them
+// to the streams they have open. A notification whose ID is already in the
+// inbox is skipped.
+func (s *NotificationService) Notify(ctx context.Context, notifications ...Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+	stored := make([]NotificationData, 0, len(notifications))
+	for _, n := range notifications {
+		id := n.ID
+		if id == uuid.Nil {
+			id = uuid.New()
+		}
+		productID := pgtype.UUID{}
+		if n.ProductID != nil {
+			productID = pgtype.UUID{Bytes: *n.ProductID, Valid: true}
+		}
+
+		row, err := qtx.CreateNotification(ctx, pgstore.CreateNotificationParams{
+			ID:        id,
+			UserID:    n.UserID,
+			Kind:      n.Kind,
+			ProductID: productID,
+			Title:     n.Title,
+			Body:      n.Body,
+		})
+		if err != nil {
+			// Delivered before, the user already has it.
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			return err
+		}
+		stored = append(stored, notificationFromRow(row))
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	for _, n := range stored {
+		s.hub.Publish(n)
+	}
+
+	return nil
+}
+
+type NotificationPage struct {
+	Notifications []NotificationData `json:"notifications"`
+	UnreadCount   int64              `json:"unread_count"`
+	NextCursor    string             `json:"next_cursor,omitempty"`
+}
+
+// List returns a page of the inbox of the user, newest first. cursor is the
+// NextCursor of the previous page or empty for the first one.
+func (s *NotificationService) List(ctx context.Context, userID uuid.UUID, unreadOnly bool, cursor string, limit int32) (NotificationPage, error) {
+	params := pgstore.ListNotificationsByUserIdParams{
+		UserID:     userID,
+		UnreadOnly: unreadOnly,
+		// Ask for one more row to know if there is a next page.
+		PageSize: limit + 1,
+	}
+
+	if cursor != "" {
+		c, err := decodeProductCursor(cursor, notificationSort)
+		if err != nil {
+			return NotificationPage{}, err
+		}
+		key, err := c.time()
+		if err != nil {
+			return NotificationPage{}, err
+		}
+		params.CursorTime = pgtype.Timestamptz{Time: key, Valid: true}
+		params.CursorID = pgtype.UUID{Bytes: c.id, Valid: true}
+	}
+
+	rows, err := s.db.ListNotificationsByUserId(ctx, params)
+	if err != nil {
+		return NotificationPage{}, err
+	}
+
+	unread, err := s.db.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		return NotificationPage{}, err
+	}
+
+	page := NotificationPage{
+		Notifications: make([]NotificationData, 0, min(len(rows), int(limit))),
+		UnreadCount:   unread,
+	}
+	for i, row := range rows {
+		if i == int(limit) {
+			last := rows[i-1]
+			next := productCursor{
+				sort: notificationSort,
+				key:  strconv.FormatInt(last.CreatedAt.Time.UnixNano(), 10),
+				id:   last.ID,
+			}
+			page.NextCursor = next.encode()
+			break
+		}
+		page.Notifications = append(page.Notifications, notificationFromRow(row))
+	}
+
+	return page, nil
+}
+
+func (s *NotificationService) UnreadCount(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.db.CountUnreadNotifications(ctx, userID)
+}
+
+// MarkRead marks a notification of the user as read, marking it twice is not an error.
+func (s *NotificationService) MarkRead(ctx context.Context, userID, id uuid.UUID) error {
+	updated, err := s.db.MarkNotificationRead(ctx, pgstore.MarkNotificationReadParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrNotificationNotFound
+	}
+
+	return nil
+}
+
+// MarkAllRead marks every unread notification of the user as read and returns how many there were.
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.db.MarkAllNotificationsRead(ctx, userID)
+}
+
+// Subscribe opens a stream of the new notifications of the user, see NotificationHub.Subscribe.
+func (s *NotificationService) Subscribe(userID uuid.UUID) (<-chan NotificationData, func()) {
+	return s.hub.Subscribe(userID)
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/oidc"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+)
+
+var (
+	ErrOIDCProviderNotFound   = errors.New("unknown identity provider")
+	ErrOIDCEmailNotVerified   = errors.New("the identity provider has not verified your email")
+	ErrOIDCAccountNotVerified = errors.New("an account with this email exists, verify its email before signing in with another provider")
+)
+
+// OIDCService signs the users in with their accounts at external identity
+// providers, such as Google or a company Keycloak.
+type OIDCService struct {
+	pool      *pgxpool.Pool
+	db        *pgstore.Queries
+	providers []*oidc.Provider
+}
+
+func NewOIDCService(pool *pgxpool.Pool, providers []*oidc.Provider) OIDCService {
+	return OIDCService{
+		pool:      pool,
+		db:        pgstore.New(pool),
+		providers: providers,
+	}
+}
+
+// OIDCProvider is what the login page needs to show the button of a provider.
+type OIDCProvider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+type UserIdentity struct {
+	ID          uuid.UUID `json:"id"`
+	Provider    string    `json:"provider"`
+	Email       string    `json:"email"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+func (s *OIDCService) Providers() []OIDCProvider {
+	providers := make([]OIDCProvider, 0, len(s.providers))
+	for _, p := range s.providers {
+		providers = append(providers, OIDCProvider{Name: p.Name(), DisplayName: p.DisplayName()})
+	}
+	return providers
+}
+
+func (s *OIDCService) Provider(name string) (*oidc.Provider, error) {
+	for _, p := range s.providers {
+		if p.Name() == name {
+			return p, nil
+		}
+	}
+	return nil, ErrOIDCProviderNotFound
+}
+
+// Login returns the user of the identity, the first login links it to the
+// account with the same email or creates one. Only the emails verified on
+// both sides are linked, otherwise whoever signed up first with an email they
+// do not own would get the account.
+func (s *OIDCService) Login(ctx context.Context, provider string, claims oidc.Claims, ip string) (uuid.UUID, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+	userID, err := qtx.UseUserIdentity(ctx, pgstore.UseUserIdentityParams{
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return uuid.UUID{}, err
+		}
+		userID, err = linkIdentity(ctx, qtx, provider, claims)
+		if err != nil {
+			return uuid.UUID{}, err
+		}
+	}
+
+	if err := recordFirstFactor(ctx, qtx, normalizeEmail(claims.Email), ip, userID); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	return userID, nil
+}
+
+func linkIdentity(ctx context.Context, qtx *pgstore.Queries, provider string, claims oidc.Claims) (uuid.UUID, error) {
+	if !claims.EmailVerified || claims.Email == "" {
+		return uuid.UUID{}, ErrOIDCEmailNotVerified
+	}
+
+	var userID uuid.UUID
+	user, err := qtx.GetUserByEmail(ctx, claims.Email)
+	switch {
+	case err == nil:
+		verified, err := qtx.IsEmailVerified(ctx, user.ID)
+		if err != nil {
+			return uuid.UUID{}, err
+		}
+		if !verified {
+			return uuid.UUID{}, ErrOIDCAccountNotVerified
+		}
+		userID = user.ID
+	case errors.Is(err, pgx.ErrNoRows):
+		userID, err = createOIDCUser(ctx, qtx, claims)
+		if err != nil {
+			return uuid.UUID{}, err
+		}
+	default:
+		return uuid.UUID{}, err
+	}
+
+	err = qtx.CreateUserIdentity(ctx, pgstore.CreateUserIdentityParams{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	return userID, nil
+}
+
+// createOIDCUser makes the account of a new user, with a random password
+// they can replace with a password reset to log in without the provider.
+func createOIDCUser(ctx context.Context, qtx *pgstore.Queries, claims oidc.Claims) (uuid.UUID, error) {
+	userName := strings.TrimSpace(claims.Name)
+	if userName == "" {
+		userName, _, _ = strings.Cut(claims.Email, "@")
+	}
+	if runes := []rune(userName); len(runes) > 255 {
+		userName = string(runes[:255])
+	}
+
+	password, _, err := newToken()
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	id, err := qtx.CreateUser(ctx, pgstore.CreateUserParams{
+		UserName:     userName,
+		Email:        normalizeEmail(claims.Email),
+		PasswordHash: hash,
+		Bio:          "",
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return uuid.UUID{}, pgstore.ErrDuplicateEmail
+		}
+		return uuid.UUID{}, err
+	}
+
+	// The provider verified it already.
+	if err := qtx.MarkEmailVerified(ctx, id); err != nil {
+		return uuid.UUID{}, err
+	}
+	if err := enqueueNotifications(ctx, qtx, welcomeNotification(id)); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	return id, nil
+}
+
+// Identities returns the providers the user signs in with.
+func (s *OIDCService) Identities(ctx context.Context, userID uuid.UUID) ([]UserIdentity, error) {
+	rows, err := s.db.ListUserIdentitiesByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	identities := make([]UserIdentity, 0, len(rows))
+	for _, row := range rows {
+		identities = append(identities, UserIdentity{
+			ID:          row.ID,
+			Provider:    row.Provider,
+			Email:       row.Email,
+			CreatedAt:   row.CreatedAt.Time,
+			LastLoginAt: row.LastLoginAt.Time,
+		})
+	}
+
+	return identities, nil
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+)
+
+// The outbox consumers, each one gets its own copy of the events written for it.
+const (
+	OutboxConsumerInbox = "inbox"
+	OutboxConsumerEmail = "email"
+)
+
+const (
+	// An event that failed this many times is dead-lettered.
+	MaxOutboxAttempts = 10
+
+	outboxBatchSize    = 10
+	outboxPollInterval = time.Second
+	// How long a consumer has to deliver a claimed event before it is claimed again.
+	outboxLease = 5 * time.Minute
+	// How long one delivery may take, a whole batch of them fits in the lease
+	// so no other instance claims the events while they are delivered.
+	outboxDeliveryTimeout = 20 * time.Second
+	maxOutboxBackoff      = time.Hour
+)
+
+var ErrOutboxEventNotFound = errors.New("outbox event not found")
+
+// notificationConsumers get every notification.
+var notificationConsumers = []string{OutboxConsumerInbox, OutboxConsumerEmail}
+
+// OutboxConsumer delivers the events written for it. An event is delivered
+// again if the process stops before it is marked delivered, eventID stays the
+// same so consumers that can, like the inbox and the emails, skip the ones
+// they already got.
+type OutboxConsumer interface {
+	Deliver(ctx context.Context, eventID uuid.UUID, payload []byte) error
+}
+
+// NotificationConsumer hands the notifications written to the outbox to a Notifier.
+type NotificationConsumer struct {
+	Notifier Notifier
+}
+
+func (c NotificationConsumer) Deliver(ctx context.Context, eventID uuid.UUID, payload []byte) error {
+	var n Notification
+	if err := json.Unmarshal(payload, &n); err != nil {
+		return err
+	}
+	n.ID = eventID
+
+	return c.Notifier.Notify(ctx, n)
+}
+
+// enqueueNotifications writes the notifications to the outbox. qtx must be in
+// the transaction of the change they are about, so they are only sent if it
+// commits and are not lost if the process stops right after.
+func enqueueNotifications(ctx context.Context, qtx *pgstore.Queries, notifications ...Notification) error {
+	for _, n := range notifications {
+		eventID := uuid.New()
+		payload, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+
+		for _, consumer := range notificationConsumers {
+			err := qtx.EnqueueOutboxEvent(ctx, pgstore.EnqueueOutboxEventParams{
+				EventID:  eventID,
+				Consumer: consumer,
+				Payload:  payload,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// OutboxService delivers the events of the outbox to their consumers,
+// retrying the failures with an exponential backoff.
+type OutboxService struct {
+	pool      *pgxpool.Pool
+	db        *pgstore.Queries
+	consumers map[string]OutboxConsumer
+}
+
+func NewOutboxService(pool *pgxpool.Pool, consumers map[string]OutboxConsumer) OutboxService {
+	return OutboxService{
+		pool:      pool,
+		db:        pgstore.New(pool),
+		consumers: consumers,
+	}
+}
+
+type OutboxEvent struct {
+	ID        uuid.UUID       `json:"id"`
+	EventID   uuid.UUID       `json:"event_id"`
+	Consumer  string          `json:"consumer"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int32           `json:"attempts"`
+	LastError string          `json:"last_error"`
+	DeadAt    *time.Time      `json:"dead_at,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Run delivers the due events until ctx is done, many instances may run at once.
+func (s *OutboxService) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while there is a backlog.
+		for {
+			claimed, err := s.dispatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("Failed to dispatch the outbox", "error", err)
+				}
+				break
+			}
+			if claimed < outboxBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch delivers one batch of due events and returns how many there were.
+func (s *OutboxService) dispatch(ctx context.Context) (int, error) {
+	leaseUntil := time.Now().Add(outboxLease)
+	events, err := s.db.ClaimOutboxEvents(ctx, pgstore.ClaimOutboxEventsParams{
+		LeaseUntil: pgtype.Timestamptz{Time: leaseUntil, Valid: true},
+		BatchSize:  outboxBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		// Should the database be slow, the events the lease has no room left
+		// for are left to be claimed again rather than delivered twice.
+		if time.Until(leaseUntil) < outboxDeliveryTimeout {
+			return len(events), nil
+		}
+		if err := s.deliver(ctx, event); err != nil {
+			return len(events), err
+		}
+	}
+
+	return len(events), nil
+}
+
+// deliver only fails when the outcome of the delivery could not be saved,
+// the event is claimed again once its lease is over.
+func (s *OutboxService) deliver(ctx context.Context, event pgstore.ClaimOutboxEventsRow) error {
+	var err error
+	if consumer, ok := s.consumers[event.Consumer]; ok {
+		deliverCtx, cancel := context.WithTimeout(ctx, outboxDeliveryTimeout)
+		err = consumer.Deliver(deliverCtx, event.EventID, event.Payload)
+		cancel()
+	} else {
+		err = fmt.Errorf("unknown outbox consumer %q", event.Consumer)
+	}
+
+	if err == nil {
+		return s.db.MarkOutboxEventDelivered(ctx, event.ID)
+	}
+
+	lastError := pgtype.Text{String: err.Error(), Valid: true}
+	if event.Attempts >= MaxOutboxAttempts {
+		slog.Error("Outbox event dead-lettered", "id", event.ID, "consumer", event.Consumer, "error", err)
+		return s.db.DeadLetterOutboxEvent(ctx, pgstore.DeadLetterOutboxEventParams{
+			ID:        event.ID,
+			LastError: lastError,
+		})
+	}
+
+	slog.Warn("Outbox delivery failed", "id", event.ID, "consumer", event.Consumer, "attempts", event.Attempts, "error", err)
+	return s.db.RetryOutboxEvent(ctx, pgstore.RetryOutboxEventParams{
+		ID:            event.ID,
+		NextAttemptAt: pgtype.Timestamptz{Time: time.Now().Add(outboxBackoff(event.Attempts)), Valid: true},
+		LastError:     lastError,
+	})
+}
+
+// outboxBackoff doubles the wait after each failed attempt, from 2 seconds up to maxOutboxBackoff.
+func outboxBackoff(attempts int32) time.Duration {
+	// Doubling past 12 attempts is over the limit anyway, and would overflow later on.
+	if attempts >= 12 {
+		return maxOutboxBackoff
+	}
+	return min(time.Second<<attempts, maxOutboxBackoff)
+}
+
+// DeadLetters returns the events that ran out of attempts, the latest first.
+func (s *OutboxService) DeadLetters(ctx context.Context, limit int32) ([]OutboxEvent, error) {
+	rows, err := s.db.ListDeadOutboxEvents(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]OutboxEvent, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, OutboxEvent{
+			ID:        row.ID,
+			EventID:   row.EventID,
+			Consumer:  row.Consumer,
+			Payload:   row.Payload,
+			Attempts:  row.Attempts,
+			LastError: row.LastError.String,
+			DeadAt:    timePtr(row.DeadAt),
+			CreatedAt: row.CreatedAt.Time,
+		})
+	}
+
+	return events, nil
+}
+
+// Requeue gives a dead-lettered event a fresh set of attempts.
+func (s *OutboxService) Requeue(ctx context.Context, id uuid.UUID) error {
+	requeued, err := s.db.RequeueDeadOutboxEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+	if requeued == 0 {
+		return ErrOutboxEventNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/mail"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+)
+
+// How long a password reset link works.
+const PasswordResetTTL = time.Hour
+
+var ErrInvalidResetToken = errors.New("the reset token is invalid, used or expired")
+
+// PasswordResetService emails the users a single use link to choose a new
+// password. The links are sent right away instead of through the outbox so
+// the token is never stored, only its hash is.
+type PasswordResetService struct {
+	pool      *pgxpool.Pool
+	db        *pgstore.Queries
+	mailer    mail.Mailer
+	templates *mail.Templates
+	// The page of the frontend that asks for the new password, the token is
+	// added to its query.
+	resetURL string
+}
+
+func NewPasswordResetService(pool *pgxpool.Pool, mailer mail.Mailer, templates *mail.Templates, resetURL string) PasswordResetService {
+	return PasswordResetService{
+		pool:      pool,
+		db:        pgstore.New(pool),
+		mailer:    mailer,
+		templates: templates,
+		resetURL:  resetURL,
+	}
+}
+
+// passwordResetEmail is what the password_reset template is rendered with.
+type passwordResetEmail struct {
+	UserName string
+	Title    string
+	URL      string
+	ValidFor string
+}
+
+// Request emails a reset link to the user with the given email. Unknown emails
+// are not an error, the caller must not tell whether the account exists.
+func (s *PasswordResetService) Request(ctx context.Context, email string) error {
+	user, err := s.db.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	token, hash, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	err = s.db.CreatePasswordResetToken(ctx, pgstore.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(PasswordResetTTL), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	link, err := tokenURL(s.resetURL, token)
+	if err != nil {
+		return err
+	}
+
+	msg, err := s.templates.Render("password_reset", passwordResetEmail{
+		UserName: user.UserName,
+		Title:    "Reset your password",
+		URL:      link,
+		ValidFor: "hour",
+	})
+	if err != nil {
+		return err
+	}
+	msg.To = user.Email
+
+	return s.mailer.Send(ctx, msg)
+}
+
+// Reset sets the password of the user the token was sent to and returns
+// their id, the token and the other pending ones stop working.
+func (s *PasswordResetService) Reset(ctx context.Context, token, password string) (uuid.UUID, error) {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+	userID, err := qtx.UsePasswordResetToken(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.UUID{}, ErrInvalidResetToken
+		}
+		return uuid.UUID{}, err
+	}
+
+	if err := setPassword(ctx, qtx, userID, hash); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	return userID, nil
+}
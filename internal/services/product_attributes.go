@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+// AttributesError lists the product attributes, or attribute filters, that
+// do not match the schema of the category.
+type AttributesError struct {
+	Problems validator.Evaluator
+}
+
+func (e *AttributesError) Error() string {
+	return fmt.Sprintf("attributes do not match the category schema: %d problems", len(e.Problems))
+}
+
+// categorySchema returns the schema a product of the category must follow:
+// the attributes of the category and of all its ancestors, the closest
+// definition winning when names collide.
+func categorySchema(ctx context.Context, q *pgstore.Queries, categoryID uuid.UUID) (validator.AttributeSchema, error) {
+	schemas, err := q.GetCategorySchemas(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if len(schemas) == 0 {
+		return nil, ErrCategoryNotFound
+	}
+
+	var merged validator.AttributeSchema
+	for _, raw := range schemas {
+		var schema validator.AttributeSchema
+		if err := json.Unmarshal(raw, &schema); err != nil {
+			return nil, fmt.Errorf("decode category schema: %w", err)
+		}
+		merged = merged.Merge(schema)
+	}
+
+	return merged, nil
+}
+
+// validateAttributes checks the values against the schema of the category
+// and encodes them for the attributes column.
+func validateAttributes(ctx context.Context, q *pgstore.Queries, categoryID uuid.UUID, values map[string]any) ([]byte, error) {
+	schema, err := categorySchema(ctx, q, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if problems := schema.ValidateValues(values); len(problems) > 0 {
+		return nil, &AttributesError{Problems: problems}
+	}
+
+	if values == nil {
+		values = map[string]any{}
+	}
+	return json.Marshal(values)
+}
+
+func decodeAttributes(raw []byte) map[string]any {
+	attributes := map[string]any{}
+	// The column is always a JSON object, written by validateAttributes.
+	_ = json.Unmarshal(raw, &attributes)
+	return attributes
+}
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	// Registers the decoders accepted for uploads.
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/authz"
+	"github.com/lohanguedes/gobid/internal/store/blobstore"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+)
+
+const (
+	MaxProductImageBytes = 5 << 20
+	maxImagesPerProduct  = 10
+	// Refuse to decode images bigger than that, a tiny file can declare a huge canvas.
+	maxImageSide = 8000
+)
+
+// Thumbnails generated for every upload, keyed by the name used in the API.
+var thumbnailSizes = []struct {
+	name    string
+	maxSide int
+}{
+	{"small", 160},
+	{"medium", 480},
+	{"large", 1024},
+}
+
+var allowedImageTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+var (
+	ErrImageTooLarge       = errors.New("image must be at most 5MB")
+	ErrUnsupportedImage    = errors.New("image must be a jpeg, png or gif")
+	ErrImageTooManyPixels  = errors.New("image must be at most 8000 pixels wide and high")
+	ErrTooManyImages       = errors.New("a product can have at most 10 images")
+	ErrProductImageMissing = errors.New("product image not found")
+)
+
+type ProductImagesService struct {
+	pool *pgxpool.Pool
+	// TODO: make this a interface in order to be more idiomatic
+	db    *pgstore.Queries
+	blobs blobstore.BlobStore
+}
+
+func NewProductImagesService(pool *pgxpool.Pool, blobs blobstore.BlobStore) ProductImagesService {
+	return ProductImagesService{
+		pool:  pool,
+		db:    pgstore.New(pool),
+		blobs: blobs,
+	}
+}
+
+type ProductImage struct {
+	ID     uuid.UUID `json:"id"`
+	URL    string    `json:"url"`
+	Width  int32     `json:"width"`
+	Height int32     `json:"height"`
+	// Thumbnail urls by size name: small, medium and large.
+	Thumbnails map[string]string `json:"thumbnails"`
+}
+
+func originalKey(productID, imageID uuid.UUID, ext string) string {
+	return fmt.Sprintf("products/%s/%s/original.%s", productID, imageID, ext)
+}
+
+func thumbnailKey(productID, imageID uuid.UUID, size string) string {
+	return fmt.Sprintf("products/%s/%s/%s.jpg", productID, imageID, size)
+}
+
+func (s *ProductImagesService) newProductImage(img pgstore.ProductImage) ProductImage {
+	data := ProductImage{
+		ID:         img.ID,
+		URL:        s.blobs.URL(img.StorageKey),
+		Width:      img.Width,
+		Height:     img.Height,
+		Thumbnails: make(map[string]string, len(thumbnailSizes)),
+	}
+	for _, size := range thumbnailSizes {
+		data.Thumbnails[size.name] = s.blobs.URL(thumbnailKey(img.ProductID, img.ID, size.name))
+	}
+	return data
+}
+
+// Upload validates the image sent by the seller or a moderator, stores it with its thumbnails
+// and appends it to the product images.
+func (s *ProductImagesService) Upload(ctx context.Context, userID, productID uuid.UUID, body io.Reader) (ProductImage, error) {
+	raw, err := io.ReadAll(io.LimitReader(body, MaxProductImageBytes+1))
+	if err != nil {
+		return ProductImage{}, err
+	}
+	if len(raw) > MaxProductImageBytes {
+		return ProductImage{}, ErrImageTooLarge
+	}
+
+	// Never trust the content type sent by the client.
+	contentType := http.DetectContentType(raw)
+	ext, ok := allowedImageTypes[contentType]
+	if !ok {
+		return ProductImage{}, ErrUnsupportedImage
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return ProductImage{}, ErrUnsupportedImage
+	}
+	if config.Width > maxImageSide || config.Height > maxImageSide {
+		return ProductImage{}, ErrImageTooManyPixels
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return ProductImage{}, ErrUnsupportedImage
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return ProductImage{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+	product, err := getProductForUpdate(ctx, qtx, productID)
+	if err != nil {
+		return ProductImage{}, err
+	}
+
+	actor, err := loadSubject(ctx, qtx, userID)
+	if err != nil {
+		return ProductImage{}, err
+	}
+	if !authz.CanManageProduct(actor, product.SellerID) {
+		return ProductImage{}, ErrNotProductSeller
+	}
+
+	// The buyers of a sold auction got what the pictures showed.
+	status := auctionStatus(product, time.Now())
+	if status != AuctionStatusUpcoming && status != AuctionStatusLive {
+		return ProductImage{}, ErrAuctionNotEditable
+	}
+
+	count, err := qtx.CountProductImages(ctx, productID)
+	if err != nil {
+		return ProductImage{}, err
+	}
+	if count >= maxImagesPerProduct {
+		return ProductImage{}, ErrTooManyImages
+	}
+
+	imageID := uuid.New()
+	keys := []string{originalKey(productID, imageID, ext)}
+	if err := s.blobs.Put(ctx, keys[0], bytes.NewReader(raw), contentType); err != nil {
+		return ProductImage{}, err
+	}
+
+	for _, size := range thumbnailSizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, thumbnail(src, size.maxSide), &jpeg.Options{Quality: 85}); err != nil {
+			s.deleteBlobs(keys)
+			return ProductImage{}, err
+		}
+
+		key := thumbnailKey(productID, imageID, size.name)
+		if err := s.blobs.Put(ctx, key, &buf, "image/jpeg"); err != nil {
+			s.deleteBlobs(keys)
+			return ProductImage{}, err
+		}
+		keys = append(keys, key)
+	}
+
+	img, err := qtx.CreateProductImage(ctx, pgstore.CreateProductImageParams{
+		ID:          imageID,
+		ProductID:   productID,
+		StorageKey:  keys[0],
+		ContentType: contentType,
+		Width:       int32(config.Width),
+		Height:      int32(config.Height),
+	})
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		s.deleteBlobs(keys)
+		return ProductImage{}, err
+	}
+
+	return s.newProductImage(img), nil
+}
+
+// deleteBlobs is best effort, a leftover blob is not worth failing the request.
+func (s *ProductImagesService) deleteBlobs(keys []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, key := range keys {
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			slog.Error("Failed to delete blob", "key", key, "error", err)
+		}
+	}
+}
+
+func (s *ProductImagesService) Delete(ctx context.Context, userID, productID, imageID uuid.UUID) error {
+	img, err := s.db.GetProductImageById(ctx, imageID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrProductImageMissing
+		}
+		return err
+	}
+
+	if img.ProductID != productID {
+		return ErrProductImageMissing
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+	product, err := getProductForUpdate(ctx, qtx, productID)
+	if err != nil {
+		return err
+	}
+
+	actor, err := loadSubject(ctx, qtx, userID)
+	if err != nil {
+		return err
+	}
+	if !authz.CanManageProduct(actor, product.SellerID) {
+		return ErrNotProductSeller
+	}
+
+	status := auctionStatus(product, time.Now())
+	if status != AuctionStatusUpcoming && status != AuctionStatusLive {
+		return ErrAuctionNotEditable
+	}
+
+	if err := qtx.DeleteProductImage(ctx, imageID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	keys := []string{img.StorageKey}
+	for _, size := range thumbnailSizes {
+		keys = append(keys, thumbnailKey(productID, imageID, size.name))
+	}
+	s.deleteBlobs(keys)
+
+	return nil
+}
+
+// ImagesByProduct returns the images of every given product, in upload order.
+func (s *ProductImagesService) ImagesByProduct(ctx context.Context, productIDs ...uuid.UUID) (map[uuid.UUID][]ProductImage, error) {
+	images, err := s.db.ListProductImagesByProductIds(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byProduct := make(map[uuid.UUID][]ProductImage, len(productIDs))
+	for _, img := range images {
+		byProduct[img.ProductID] = append(byProduct[img.ProductID], s.newProductImage(img))
+	}
+	return byProduct, nil
+}
+
+// thumbnail scales src down to fit a maxSide square using a box filter,
+// transparent pixels are flattened over white since the output is a jpeg.
+func thumbnail(src image.Image, maxSide int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	dw, dh := w, h
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			dw, dh = maxSide, max(1, h*maxSide/w)
+		} else {
+			dw, dh = max(1, w*maxSide/h), maxSide
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		sy0 := bounds.Min.Y + y*h/dh
+		sy1 := max(bounds.Min.Y+(y+1)*h/dh, sy0+1)
+		for x := 0; x < dw; x++ {
+			sx0 := bounds.Min.X + x*w/dw
+			sx1 := max(bounds.Min.X+(x+1)*w/dw, sx0+1)
+
+			var r, g, b, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					// Colors are alpha premultiplied, adding the missing alpha composes over white.
+					r += uint64(cr + 0xffff - ca)
+					g += uint64(cg + 0xffff - ca)
+					b += uint64(cb + 0xffff - ca)
+					n++
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: 0xffff,
+			})
+		}
+	}
+
+	return dst
+}
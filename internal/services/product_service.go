@@ -2,14 +2,25 @@ package services
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"html"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/authz"
 	"github.com/lohanguedes/gobid/internal/store/pgstore"
+	"github.com/lohanguedes/gobid/internal/validator"
 )
 
 var ErrProductNotFound = errors.New("product not found in database")
@@ -31,18 +42,32 @@ func NewProductService(pool *pgxpool.Pool) ProductService {
 func (s *ProductService) CreateProduct(
 	ctx context.Context,
 	sellerID uuid.UUID,
-	productName string,
+	categoryID uuid.UUID,
+	productName, description string,
+	attributes map[string]any,
 	basePrice float64,
 	auctionStart, auctionEnd pgtype.Timestamptz,
 ) (uuid.UUID, error) {
+	encodedAttributes, err := validateAttributes(ctx, s.db, categoryID, attributes)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
 	id, err := s.db.CreateProduct(ctx, pgstore.CreateProductParams{
 		SellerID:     sellerID,
 		ProductName:  productName,
+		Description:  description,
 		BasePrice:    basePrice,
 		AuctionStart: auctionStart,
 		AuctionEnd:   auctionEnd,
+		CategoryID:   pgtype.UUID{Bytes: categoryID, Valid: true},
+		Attributes:   encodedAttributes,
 	})
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return uuid.UUID{}, ErrCategoryNotFound
+		}
 		return uuid.UUID{}, err
 	}
 
@@ -58,6 +83,47 @@ type ProductData struct {
 	AuctionStart time.Time `json:"auction_start"`
 	AuctionEnd   time.Time `json:"auction_end"`
 	IsSold       bool      `json:"is_sold"`
+	// Set once the seller cancelled the auction.
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+	// Products listed before categories existed have none.
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+	// Values of the attributes defined by the category, keyed by name.
+	Attributes map[string]any `json:"attributes"`
+	// Filled by the callers that show the product, see ProductImagesService.
+	Images []ProductImage `json:"images,omitempty"`
+}
+
+func timePtr(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func uuidPtr(id pgtype.UUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	u := uuid.UUID(id.Bytes)
+	return &u
+}
+
+// productFromRow maps the rows of GetProductById and, converted, of
+// GetProductByIdForUpdate, the two queries select the same columns.
+func productFromRow(row pgstore.GetProductByIdRow) ProductData {
+	return ProductData{
+		ID:           row.ID,
+		SellerID:     row.SellerID,
+		ProductName:  row.ProductName,
+		Description:  row.Description,
+		BasePrice:    row.BasePrice,
+		AuctionStart: row.AuctionStart.Time,
+		AuctionEnd:   row.AuctionEnd.Time,
+		IsSold:       row.IsSold,
+		CancelledAt:  timePtr(row.CancelledAt),
+		CategoryID:   uuidPtr(row.CategoryID),
+		Attributes:   decodeAttributes(row.Attributes),
+	}
 }
 
 func (s *ProductService) GetProductById(ctx context.Context, id uuid.UUID) (ProductData, error) {
@@ -69,14 +135,839 @@ func (s *ProductService) GetProductById(ctx context.Context, id uuid.UUID) (Prod
 		return ProductData{}, err
 	}
 
-	return ProductData{
-		product.ID,
-		product.SellerID,
-		product.ProductName,
-		product.Description,
-		product.BasePrice,
-		product.AuctionStart.Time,
-		product.AuctionEnd.Time,
-		product.IsSold,
-	}, nil
+	return productFromRow(product), nil
+}
+
+// getProductForUpdate locks the product row until the transaction of qtx ends.
+func getProductForUpdate(ctx context.Context, qtx *pgstore.Queries, id uuid.UUID) (ProductData, error) {
+	product, err := qtx.GetProductByIdForUpdate(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ProductData{}, ErrProductNotFound
+		}
+		return ProductData{}, err
+	}
+
+	return productFromRow(pgstore.GetProductByIdRow(product)), nil
+}
+
+// Auction status, derived from the auction dates and is_sold.
+const (
+	AuctionStatusUpcoming  = "upcoming"
+	AuctionStatusLive      = "live"
+	AuctionStatusEnded     = "ended"
+	AuctionStatusSold      = "sold"
+	AuctionStatusCancelled = "cancelled"
+)
+
+// Listing sort orders.
+const (
+	SortNewest     = "newest"
+	SortEndingSoon = "ending_soon"
+)
+
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// ProductFilter holds the optional filters of a product listing, nil means not filtered.
+type ProductFilter struct {
+	Status   string
+	SellerID *uuid.UUID
+	MinPrice *float64
+	MaxPrice *float64
+	// Matches the category and all of its descendants.
+	CategoryID *uuid.UUID
+	// Raw attribute filters from the query string, see
+	// validator.AttributeSchema.ParseFilters. They require CategoryID.
+	Attributes map[string]string
+}
+
+// ProductListing is a product with the state of its auction.
+type ProductListing struct {
+	ProductData
+	Status     string  `json:"status"`
+	HighestBid float64 `json:"highest_bid"`
+	BidCount   int64   `json:"bid_count"`
+}
+
+func auctionStatus(p ProductData, now time.Time) string {
+	switch {
+	case p.IsSold:
+		return AuctionStatusSold
+	case p.CancelledAt != nil:
+		return AuctionStatusCancelled
+	case now.Before(p.AuctionStart):
+		return AuctionStatusUpcoming
+	case now.Before(p.AuctionEnd):
+		return AuctionStatusLive
+	default:
+		return AuctionStatusEnded
+	}
+}
+
+type ProductDetails struct {
+	ProductListing
+	RemainingSeconds int64 `json:"remaining_seconds"`
+	// Whether a websocket auction room can be joined right now.
+	HasLiveRoom bool `json:"has_live_room"`
+}
+
+// GetProductDetails returns the product with the current state of its auction,
+// HasLiveRoom is left for the caller since rooms live in the AuctionLobby.
+func (s *ProductService) GetProductDetails(ctx context.Context, id uuid.UUID) (ProductDetails, error) {
+	product, err := s.GetProductById(ctx, id)
+	if err != nil {
+		return ProductDetails{}, err
+	}
+
+	stats, err := s.db.GetBidStatsByProductId(ctx, id)
+	if err != nil {
+		return ProductDetails{}, err
+	}
+
+	now := time.Now()
+	details := ProductDetails{
+		ProductListing: ProductListing{
+			ProductData: product,
+			Status:      auctionStatus(product, now),
+			HighestBid:  stats.HighestBid,
+			BidCount:    stats.BidCount,
+		},
+	}
+	if details.Status == AuctionStatusLive || details.Status == AuctionStatusUpcoming {
+		details.RemainingSeconds = int64(product.AuctionEnd.Sub(now).Seconds())
+	}
+
+	return details, nil
+}
+
+type ProductPage struct {
+	Products   []ProductListing `json:"products"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// A cursor points to the last row of a page: the sort it belongs to, that row sort key and id.
+type productCursor struct {
+	sort string
+	key  string
+	id   uuid.UUID
+}
+
+func (c productCursor) encode() string {
+	raw := fmt.Sprintf("%s|%s|%s", c.sort, c.key, c.id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func (c productCursor) time() (time.Time, error) {
+	nanos, err := strconv.ParseInt(c.key, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrInvalidCursor
+	}
+	return time.Unix(0, nanos), nil
+}
+
+func (c productCursor) float() (float64, error) {
+	f, err := strconv.ParseFloat(c.key, 64)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	return f, nil
+}
+
+// decodeProductCursor also checks that the cursor was made for the given sort.
+func decodeProductCursor(cursor, sort string) (productCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return productCursor{}, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 || parts[0] != sort {
+		return productCursor{}, ErrInvalidCursor
+	}
+
+	id, err := uuid.Parse(parts[2])
+	if err != nil {
+		return productCursor{}, ErrInvalidCursor
+	}
+
+	return productCursor{sort: parts[0], key: parts[1], id: id}, nil
+}
+
+// productFilterParams are the filter arguments shared by the listing and search queries.
+type productFilterParams struct {
+	Status          pgtype.Text
+	SellerID        pgtype.UUID
+	MinPrice        pgtype.Float8
+	MaxPrice        pgtype.Float8
+	CategoryID      pgtype.UUID
+	Attributes      []byte
+	AttributeRanges []byte
+}
+
+func (s *ProductService) filterParams(ctx context.Context, f ProductFilter) (productFilterParams, error) {
+	var params productFilterParams
+	if f.Status != "" {
+		params.Status = pgtype.Text{String: f.Status, Valid: true}
+	}
+	if f.SellerID != nil {
+		params.SellerID = pgtype.UUID{Bytes: *f.SellerID, Valid: true}
+	}
+	if f.MinPrice != nil {
+		params.MinPrice = pgtype.Float8{Float64: *f.MinPrice, Valid: true}
+	}
+	if f.MaxPrice != nil {
+		params.MaxPrice = pgtype.Float8{Float64: *f.MaxPrice, Valid: true}
+	}
+	if f.CategoryID != nil {
+		params.CategoryID = pgtype.UUID{Bytes: *f.CategoryID, Valid: true}
+	}
+
+	if len(f.Attributes) > 0 {
+		if f.CategoryID == nil {
+			return productFilterParams{}, &AttributesError{Problems: validator.Evaluator{
+				"category_id": "filtering by attributes requires a category",
+			}}
+		}
+		schema, err := categorySchema(ctx, s.db, *f.CategoryID)
+		if err != nil {
+			return productFilterParams{}, err
+		}
+		equals, ranges, problems := schema.ParseFilters(f.Attributes)
+		if len(problems) > 0 {
+			return productFilterParams{}, &AttributesError{Problems: problems}
+		}
+		if len(equals) > 0 {
+			if params.Attributes, err = json.Marshal(equals); err != nil {
+				return productFilterParams{}, err
+			}
+		}
+		if len(ranges) > 0 {
+			if params.AttributeRanges, err = json.Marshal(ranges); err != nil {
+				return productFilterParams{}, err
+			}
+		}
+	}
+
+	return params, nil
+}
+
+// ListProducts returns a page of products, cursor is the NextCursor of the
+// previous page or empty for the first one. SortEndingSoon leaves out the
+// auctions that already ended.
+func (s *ProductService) ListProducts(ctx context.Context, filter ProductFilter, sort, cursor string, limit int32) (ProductPage, error) {
+	if sort == "" {
+		sort = SortNewest
+	}
+
+	filters, err := s.filterParams(ctx, filter)
+	if err != nil {
+		return ProductPage{}, err
+	}
+	params := pgstore.ListProductsParams{
+		Status:          filters.Status,
+		SellerID:        filters.SellerID,
+		MinPrice:        filters.MinPrice,
+		MaxPrice:        filters.MaxPrice,
+		CategoryID:      filters.CategoryID,
+		Attributes:      filters.Attributes,
+		AttributeRanges: filters.AttributeRanges,
+		Sort:            sort,
+		// Ask for one more row to know if there is a next page.
+		PageSize: limit + 1,
+	}
+
+	if cursor != "" {
+		c, err := decodeProductCursor(cursor, sort)
+		if err != nil {
+			return ProductPage{}, err
+		}
+		key, err := c.time()
+		if err != nil {
+			return ProductPage{}, err
+		}
+		params.CursorTime = pgtype.Timestamptz{Time: key, Valid: true}
+		params.CursorID = pgtype.UUID{Bytes: c.id, Valid: true}
+	}
+
+	rows, err := s.db.ListProducts(ctx, params)
+	if err != nil {
+		return ProductPage{}, err
+	}
+
+	now := time.Now()
+	page := ProductPage{Products: make([]ProductListing, 0, min(len(rows), int(limit)))}
+	for i, row := range rows {
+		if i == int(limit) {
+			last := rows[i-1]
+			key := last.CreatedAt.Time
+			if sort == SortEndingSoon {
+				key = last.AuctionEnd.Time
+			}
+			next := productCursor{sort: sort, key: strconv.FormatInt(key.UnixNano(), 10), id: last.ID}
+			page.NextCursor = next.encode()
+			break
+		}
+
+		product := ProductData{
+			ID:           row.ID,
+			SellerID:     row.SellerID,
+			ProductName:  row.ProductName,
+			Description:  row.Description,
+			BasePrice:    row.BasePrice,
+			AuctionStart: row.AuctionStart.Time,
+			AuctionEnd:   row.AuctionEnd.Time,
+			IsSold:       row.IsSold,
+			CancelledAt:  timePtr(row.CancelledAt),
+			CategoryID:   uuidPtr(row.CategoryID),
+			Attributes:   decodeAttributes(row.Attributes),
+		}
+		page.Products = append(page.Products, ProductListing{
+			ProductData: product,
+			Status:      auctionStatus(product, now),
+			HighestBid:  row.HighestBid,
+			BidCount:    row.BidCount,
+		})
+	}
+
+	return page, nil
+}
+
+// searchSort is only used to tell search cursors apart from listing ones.
+const searchSort = "search"
+
+type ProductSearchResult struct {
+	ProductListing
+	Rank float64 `json:"rank"`
+	// HTML escaped text where the matched words are wrapped in <mark> tags.
+	NameHighlight string `json:"name_highlight"`
+	Snippet       string `json:"snippet"`
+}
+
+type ProductSearchPage struct {
+	Results    []ProductSearchResult `json:"results"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+// highlight escapes the ts_headline output keeping only the <mark> tags it added.
+func highlight(headline string) string {
+	escaped := html.EscapeString(headline)
+	escaped = strings.ReplaceAll(escaped, "&lt;mark&gt;", "<mark>")
+	return strings.ReplaceAll(escaped, "&lt;/mark&gt;", "</mark>")
+}
+
+// Search returns the products matching query, best matches first. Query
+// accepts the web search syntax: "quoted phrases", or and -excluded words.
+func (s *ProductService) Search(ctx context.Context, query string, filter ProductFilter, cursor string, limit int32) (ProductSearchPage, error) {
+	filters, err := s.filterParams(ctx, filter)
+	if err != nil {
+		return ProductSearchPage{}, err
+	}
+	params := pgstore.SearchProductsParams{
+		Query:           query,
+		Status:          filters.Status,
+		SellerID:        filters.SellerID,
+		MinPrice:        filters.MinPrice,
+		MaxPrice:        filters.MaxPrice,
+		CategoryID:      filters.CategoryID,
+		Attributes:      filters.Attributes,
+		AttributeRanges: filters.AttributeRanges,
+		// Ask for one more row to know if there is a next page.
+		PageSize: limit + 1,
+	}
+
+	if cursor != "" {
+		c, err := decodeProductCursor(cursor, searchSort)
+		if err != nil {
+			return ProductSearchPage{}, err
+		}
+		rank, err := c.float()
+		if err != nil {
+			return ProductSearchPage{}, err
+		}
+		params.CursorRank = pgtype.Float8{Float64: rank, Valid: true}
+		params.CursorID = pgtype.UUID{Bytes: c.id, Valid: true}
+	}
+
+	rows, err := s.db.SearchProducts(ctx, params)
+	if err != nil {
+		return ProductSearchPage{}, err
+	}
+
+	now := time.Now()
+	page := ProductSearchPage{Results: make([]ProductSearchResult, 0, min(len(rows), int(limit)))}
+	for i, row := range rows {
+		if i == int(limit) {
+			last := rows[i-1]
+			next := productCursor{sort: searchSort, key: strconv.FormatFloat(last.Rank, 'g', -1, 64), id: last.ID}
+			page.NextCursor = next.encode()
+			break
+		}
+
+		product := ProductData{
+			ID:           row.ID,
+			SellerID:     row.SellerID,
+			ProductName:  row.ProductName,
+			Description:  row.Description,
+			BasePrice:    row.BasePrice,
+			AuctionStart: row.AuctionStart.Time,
+			AuctionEnd:   row.AuctionEnd.Time,
+			IsSold:       row.IsSold,
+			CancelledAt:  timePtr(row.CancelledAt),
+			CategoryID:   uuidPtr(row.CategoryID),
+			Attributes:   decodeAttributes(row.Attributes),
+		}
+		page.Results = append(page.Results, ProductSearchResult{
+			ProductListing: ProductListing{
+				ProductData: product,
+				Status:      auctionStatus(product, now),
+				HighestBid:  row.HighestBid,
+				BidCount:    row.BidCount,
+			},
+			Rank:          row.Rank,
+			NameHighlight: highlight(row.NameHighlight),
+			Snippet:       highlight(row.Snippet),
+		})
+	}
+
+	return page, nil
+}
+
+const minAuctionDuration = 2 * time.Hour
+
+var (
+	ErrNotProductSeller       = errors.New("you are not allowed to manage this product")
+	ErrAuctionNotEditable     = errors.New("sold, ended or cancelled auctions cannot be changed")
+	ErrProductLockedAfterBids = errors.New("only the description can be changed once the auction has bids")
+	ErrAuctionAlreadyStarted  = errors.New("the auction start cannot be changed once it started")
+	ErrInvalidAuctionSchedule = errors.New("auction end must be in the future and at least 2 hours after auction start")
+	ErrProductNotRelistable   = errors.New("only auctions that ended without bids or were cancelled can be relisted")
+)
+
+// UpdateProductParams holds the fields to change, nil fields are kept as they are.
+type UpdateProductParams struct {
+	ProductName  *string
+	Description  *string
+	BasePrice    *float64
+	AuctionStart *time.Time
+	AuctionEnd   *time.Time
+	// Replaces all the attributes of the product.
+	Attributes map[string]any
+}
+
+// UpdateProduct changes an upcoming or live auction for its seller or a
+// moderator. Once the auction has bids only the description may change.
+func (s *ProductService) UpdateProduct(ctx context.Context, userID, productID uuid.UUID, params UpdateProductParams) (ProductData, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return ProductData{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+	product, err := getProductForUpdate(ctx, qtx, productID)
+	if err != nil {
+		return ProductData{}, err
+	}
+
+	actor, err := loadSubject(ctx, qtx, userID)
+	if err != nil {
+		return ProductData{}, err
+	}
+	if !authz.CanManageProduct(actor, product.SellerID) {
+		return ProductData{}, ErrNotProductSeller
+	}
+
+	now := time.Now()
+	status := auctionStatus(product, now)
+	if status != AuctionStatusUpcoming && status != AuctionStatusLive {
+		return ProductData{}, ErrAuctionNotEditable
+	}
+
+	stats, err := qtx.GetBidStatsByProductId(ctx, productID)
+	if err != nil {
+		return ProductData{}, err
+	}
+
+	if stats.BidCount > 0 &&
+		(params.ProductName != nil || params.BasePrice != nil || params.AuctionStart != nil || params.AuctionEnd != nil || params.Attributes != nil) {
+		return ProductData{}, ErrProductLockedAfterBids
+	}
+
+	if status == AuctionStatusLive && params.AuctionStart != nil {
+		return ProductData{}, ErrAuctionAlreadyStarted
+	}
+
+	if params.ProductName != nil {
+		product.ProductName = *params.ProductName
+	}
+	if params.Description != nil {
+		product.Description = *params.Description
+	}
+	if params.BasePrice != nil {
+		product.BasePrice = *params.BasePrice
+	}
+	if params.AuctionStart != nil {
+		product.AuctionStart = *params.AuctionStart
+	}
+	if params.AuctionEnd != nil {
+		product.AuctionEnd = *params.AuctionEnd
+	}
+
+	if !product.AuctionEnd.After(now) || product.AuctionEnd.Sub(product.AuctionStart) < minAuctionDuration {
+		return ProductData{}, ErrInvalidAuctionSchedule
+	}
+
+	encodedAttributes, err := json.Marshal(product.Attributes)
+	if err != nil {
+		return ProductData{}, err
+	}
+	if params.Attributes != nil {
+		if product.CategoryID == nil {
+			return ProductData{}, &AttributesError{Problems: validator.Evaluator{
+				"attributes": "the product has no category to define attributes",
+			}}
+		}
+		encodedAttributes, err = validateAttributes(ctx, qtx, *product.CategoryID, params.Attributes)
+		if err != nil {
+			return ProductData{}, err
+		}
+		product.Attributes = params.Attributes
+	}
+
+	err = qtx.UpdateProduct(ctx, pgstore.UpdateProductParams{
+		ID:           product.ID,
+		ProductName:  product.ProductName,
+		Description:  product.Description,
+		BasePrice:    product.BasePrice,
+		AuctionStart: pgtype.Timestamptz{Time: product.AuctionStart, Valid: true},
+		AuctionEnd:   pgtype.Timestamptz{Time: product.AuctionEnd, Valid: true},
+		Attributes:   encodedAttributes,
+	})
+	if err != nil {
+		return ProductData{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return ProductData{}, err
+	}
+
+	return product, nil
+}
+
+// CancelProduct cancels an upcoming or live auction for its seller or a
+// moderator, the seller is told when it was a moderator.
+func (s *ProductService) CancelProduct(ctx context.Context, userID, productID uuid.UUID) error {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+	product, err := getProductForUpdate(ctx, qtx, productID)
+	if err != nil {
+		return err
+	}
+
+	actor, err := loadSubject(ctx, qtx, userID)
+	if err != nil {
+		return err
+	}
+	if !authz.CanManageProduct(actor, product.SellerID) {
+		return ErrNotProductSeller
+	}
+
+	status := auctionStatus(product, time.Now())
+	if status != AuctionStatusUpcoming && status != AuctionStatusLive {
+		return ErrAuctionNotEditable
+	}
+
+	if err := qtx.CancelProduct(ctx, productID); err != nil {
+		return err
+	}
+
+	// Bidders watch the auctions they bid on, so they are told too.
+	watchers, err := qtx.ListWatchersByProductId(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	cancelledBy := "the seller"
+	if actor.UserID != product.SellerID {
+		cancelledBy = "a moderator"
+		if !slices.Contains(watchers, product.SellerID) {
+			watchers = append(watchers, product.SellerID)
+		}
+	}
+
+	notifications := make([]Notification, 0, len(watchers))
+	for _, watcherID := range watchers {
+		notifications = append(notifications, Notification{
+			UserID:    watcherID,
+			Kind:      NotificationAuctionCancelled,
+			ProductID: &product.ID,
+			Title:     "Auction cancelled",
+			Body:      fmt.Sprintf("%s was cancelled by %s.", product.ProductName, cancelledBy),
+		})
+	}
+	if err := enqueueNotifications(ctx, qtx, notifications...); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RelistProduct clones an unsold auction of the seller into a new one,
+// keeping the old base price when basePrice is nil.
+func (s *ProductService) RelistProduct(
+	ctx context.Context,
+	userID, productID uuid.UUID,
+	basePrice *float64,
+	auctionStart, auctionEnd pgtype.Timestamptz,
+) (uuid.UUID, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+	product, err := getProductForUpdate(ctx, qtx, productID)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	actor, err := loadSubject(ctx, qtx, userID)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	if !authz.CanRelistProduct(actor, product.SellerID) {
+		return uuid.UUID{}, ErrNotProductSeller
+	}
+
+	switch auctionStatus(product, time.Now()) {
+	case AuctionStatusCancelled:
+	case AuctionStatusEnded:
+		// An ended auction with bids has a winner, it is not unsold.
+		stats, err := qtx.GetBidStatsByProductId(ctx, productID)
+		if err != nil {
+			return uuid.UUID{}, err
+		}
+		if stats.BidCount > 0 {
+			return uuid.UUID{}, ErrProductNotRelistable
+		}
+	default:
+		return uuid.UUID{}, ErrProductNotRelistable
+	}
+
+	price := product.BasePrice
+	if basePrice != nil {
+		price = *basePrice
+	}
+
+	id, err := qtx.RelistProduct(ctx, pgstore.RelistProductParams{
+		ID:           productID,
+		BasePrice:    price,
+		AuctionStart: auctionStart,
+		AuctionEnd:   auctionEnd,
+	})
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	return id, nil
+}
+
+var (
+	ErrAuctionNotEnded       = errors.New("the auction has not ended yet")
+	ErrAuctionAlreadySettled = errors.New("the auction was already settled")
+)
+
+// NotifyEndingSoon tells the watchers of the live auctions ending before
+// endsBefore, once per auction.
+func (s *ProductService) NotifyEndingSoon(ctx context.Context, endsBefore time.Time) error {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+	products, err := qtx.ClaimEndingSoonAuctions(ctx, pgtype.Timestamptz{Time: endsBefore, Valid: true})
+	if err != nil {
+		return err
+	}
+
+	for _, product := range products {
+		watchers, err := qtx.ListWatchersByProductId(ctx, product.ID)
+		if err != nil {
+			return err
+		}
+
+		minutes := int(math.Ceil(time.Until(product.AuctionEnd.Time).Minutes()))
+		notifications := make([]Notification, 0, len(watchers))
+		for _, userID := range watchers {
+			notifications = append(notifications, Notification{
+				UserID:    userID,
+				Kind:      NotificationAuctionEndingSoon,
+				ProductID: &product.ID,
+				Title:     "Auction ending soon",
+				Body:      fmt.Sprintf("%s ends in %d minutes.", product.ProductName, minutes),
+			})
+		}
+		if err := enqueueNotifications(ctx, qtx, notifications...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UnsettledAuctions returns up to limit ended auctions that still have to be settled.
+func (s *ProductService) UnsettledAuctions(ctx context.Context, limit int32) ([]uuid.UUID, error) {
+	return s.db.ListUnsettledAuctions(ctx, limit)
+}
+
+// AuctionResult is the outcome of a settled auction, WinnerID is nil when
+// nobody placed a bid.
+type AuctionResult struct {
+	Product    ProductData
+	WinnerID   *uuid.UUID
+	WinningBid float64
+}
+
+// SettleAuction closes an ended auction: it is sold to the highest bidder if
+// there is one. An auction is settled only once, the winner, the seller and
+// the watchers are told how it went and the webhooks of the seller get the
+// auction.ended and item.sold events.
+func (s *ProductService) SettleAuction(ctx context.Context, productID uuid.UUID) (AuctionResult, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return AuctionResult{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+	product, err := getProductForUpdate(ctx, qtx, productID)
+	if err != nil {
+		return AuctionResult{}, err
+	}
+
+	if product.AuctionEnd.After(time.Now()) {
+		return AuctionResult{}, ErrAuctionNotEnded
+	}
+
+	result := AuctionResult{Product: product}
+	highestBid, err := qtx.GetHighestBidByProductId(ctx, productID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return AuctionResult{}, err
+	}
+	if err == nil {
+		result.WinnerID = &highestBid.BidderID
+		result.WinningBid = highestBid.BidAmount
+	}
+
+	settled, err := qtx.SettleProduct(ctx, pgstore.SettleProductParams{
+		ID:     productID,
+		IsSold: result.WinnerID != nil,
+	})
+	if err != nil {
+		return AuctionResult{}, err
+	}
+	if settled == 0 {
+		return AuctionResult{}, ErrAuctionAlreadySettled
+	}
+	result.Product.IsSold = result.WinnerID != nil
+
+	watchers, err := qtx.ListWatchersByProductId(ctx, productID)
+	if err != nil {
+		return AuctionResult{}, err
+	}
+	if err := enqueueNotifications(ctx, qtx, auctionEndedNotifications(result, watchers)...); err != nil {
+		return AuctionResult{}, err
+	}
+
+	ended := AuctionEndedEvent{
+		ProductID:   product.ID,
+		ProductName: product.ProductName,
+		Sold:        result.Product.IsSold,
+		WinnerID:    result.WinnerID,
+		EndedAt:     product.AuctionEnd,
+	}
+	if result.WinnerID != nil {
+		ended.WinningBid = &result.WinningBid
+	}
+	if err := enqueueWebhookEvent(ctx, qtx, product.SellerID, WebhookAuctionEnded, ended); err != nil {
+		return AuctionResult{}, err
+	}
+	if result.WinnerID != nil {
+		err := enqueueWebhookEvent(ctx, qtx, product.SellerID, WebhookItemSold, ItemSoldEvent{
+			ProductID:   product.ID,
+			ProductName: product.ProductName,
+			BuyerID:     *result.WinnerID,
+			Amount:      result.WinningBid,
+		})
+		if err != nil {
+			return AuctionResult{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return AuctionResult{}, err
+	}
+
+	return result, nil
+}
+
+// auctionEndedNotifications tells the winner and the seller how the auction
+// went, and the other watchers that it ended.
+func auctionEndedNotifications(result AuctionResult, watchers []uuid.UUID) []Notification {
+	product := result.Product
+	notifications := make([]Notification, 0, len(watchers)+2)
+
+	seller := Notification{
+		UserID:    product.SellerID,
+		Kind:      NotificationAuctionEnded,
+		ProductID: &product.ID,
+		Title:     "Auction ended",
+		Body:      fmt.Sprintf("%s ended without bids.", product.ProductName),
+	}
+	if result.WinnerID != nil {
+		seller.Kind = NotificationItemSold
+		seller.Title = "Item sold"
+		seller.Body = fmt.Sprintf("%s sold for %.2f.", product.ProductName, result.WinningBid)
+
+		notifications = append(notifications, Notification{
+			UserID:    *result.WinnerID,
+			Kind:      NotificationAuctionWon,
+			ProductID: &product.ID,
+			Title:     "You won the auction",
+			Body:      fmt.Sprintf("You won %s with a bid of %.2f.", product.ProductName, result.WinningBid),
+		})
+	}
+	notifications = append(notifications, seller)
+
+	for _, userID := range watchers {
+		if userID == product.SellerID || (result.WinnerID != nil && userID == *result.WinnerID) {
+			continue
+		}
+
+		body := fmt.Sprintf("%s ended without bids.", product.ProductName)
+		if result.WinnerID != nil {
+			body = fmt.Sprintf("%s ended with a winning bid of %.2f.", product.ProductName, result.WinningBid)
+		}
+		notifications = append(notifications, Notification{
+			UserID:    userID,
+			Kind:      NotificationAuctionEnded,
+			ProductID: &product.ID,
+			Title:     "Auction ended",
+			Body:      body,
+		})
+	}
+
+	return notifications
 }
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+)
+
+// How many of the latest ratings a profile shows.
+const recentRatings = 10
+
+var (
+	ErrNotAuctionBuyer = errors.New("only the buyer of a sold auction may rate its seller")
+	ErrAlreadyRated    = errors.New("the seller was already rated for this auction")
+)
+
+type RatingService struct {
+	pool *pgxpool.Pool
+	db   *pgstore.Queries
+}
+
+func NewRatingService(pool *pgxpool.Pool) RatingService {
+	return RatingService{
+		pool: pool,
+		db:   pgstore.New(pool),
+	}
+}
+
+type SellerRating struct {
+	ProductID uuid.UUID `json:"product_id"`
+	BuyerID   uuid.UUID `json:"buyer_id"`
+	BuyerName string    `json:"buyer_name"`
+	Score     int32     `json:"score"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RatingSummary struct {
+	// Zero when there are no ratings.
+	Average float64        `json:"average"`
+	Count   int64          `json:"count"`
+	Recent  []SellerRating `json:"recent"`
+}
+
+// RateSeller lets the buyer of a sold auction rate its seller, once.
+func (s *RatingService) RateSeller(ctx context.Context, buyerID, productID uuid.UUID, score int32, comment string) error {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+	product, err := getProductForUpdate(ctx, qtx, productID)
+	if err != nil {
+		return err
+	}
+	if !product.IsSold {
+		return ErrNotAuctionBuyer
+	}
+
+	// The auction was sold to its highest bidder.
+	highestBid, err := qtx.GetHighestBidByProductId(ctx, productID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotAuctionBuyer
+		}
+		return err
+	}
+	if highestBid.BidderID != buyerID {
+		return ErrNotAuctionBuyer
+	}
+
+	err = qtx.CreateSellerRating(ctx, pgstore.CreateSellerRatingParams{
+		ProductID: productID,
+		SellerID:  product.SellerID,
+		BuyerID:   buyerID,
+		Score:     score,
+		Comment:   comment,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrAlreadyRated
+		}
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Summary returns the average rating of the seller and the latest ones.
+func (s *RatingService) Summary(ctx context.Context, sellerID uuid.UUID) (RatingSummary, error) {
+	summary, err := s.db.GetSellerRatingSummary(ctx, sellerID)
+	if err != nil {
+		return RatingSummary{}, err
+	}
+
+	rows, err := s.db.ListSellerRatings(ctx, pgstore.ListSellerRatingsParams{
+		SellerID: sellerID,
+		Limit:    recentRatings,
+	})
+	if err != nil {
+		return RatingSummary{}, err
+	}
+
+	ratings := RatingSummary{
+		Average: summary.Average,
+		Count:   summary.Count,
+		Recent:  make([]SellerRating, 0, len(rows)),
+	}
+	for _, row := range rows {
+		ratings.Recent = append(ratings.Recent, SellerRating{
+			ProductID: row.ProductID,
+			BuyerID:   row.BuyerID,
+			BuyerName: row.BuyerName,
+			Score:     row.Score,
+			Comment:   row.Comment,
+			CreatedAt: row.CreatedAt.Time,
+		})
+	}
+
+	return ratings, nil
+}
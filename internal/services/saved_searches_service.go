@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+const (
+	MaxSavedSearchesPerUser = 20
+
+	matchBatchSize = 100
+)
+
+var (
+	ErrSavedSearchNotFound  = errors.New("saved search not found")
+	ErrTooManySavedSearches = errors.New("too many saved searches, delete one first")
+)
+
+type SavedSearchService struct {
+	pool *pgxpool.Pool
+	db   *pgstore.Queries
+}
+
+func NewSavedSearchService(pool *pgxpool.Pool) SavedSearchService {
+	return SavedSearchService{
+		pool: pool,
+		db:   pgstore.New(pool),
+	}
+}
+
+// SavedSearchParams are the search filters to save, the same ones
+// ProductService.Search accepts.
+type SavedSearchParams struct {
+	Name       string
+	Query      string
+	CategoryID *uuid.UUID
+	MinPrice   *float64
+	MaxPrice   *float64
+	// Raw attribute filters, see ProductFilter.Attributes.
+	Attributes map[string]string
+}
+
+type SavedSearch struct {
+	ID              uuid.UUID                           `json:"id"`
+	Name            string                              `json:"name"`
+	Query           string                              `json:"q"`
+	CategoryID      *uuid.UUID                          `json:"category_id,omitempty"`
+	MinPrice        *float64                            `json:"min_price,omitempty"`
+	MaxPrice        *float64                            `json:"max_price,omitempty"`
+	Attributes      map[string]any                      `json:"attributes"`
+	AttributeRanges map[string]validator.AttributeRange `json:"attribute_ranges"`
+	CreatedAt       time.Time                           `json:"created_at"`
+}
+
+func float8Ptr(f pgtype.Float8) *float64 {
+	if !f.Valid {
+		return nil
+	}
+	return &f.Float64
+}
+
+func float8(f *float64) pgtype.Float8 {
+	if f == nil {
+		return pgtype.Float8{}
+	}
+	return pgtype.Float8{Float64: *f, Valid: true}
+}
+
+func savedSearchFromRow(row pgstore.SavedSearch) SavedSearch {
+	search := SavedSearch{
+		ID:              row.ID,
+		Name:            row.Name,
+		Query:           row.Query,
+		CategoryID:      uuidPtr(row.CategoryID),
+		MinPrice:        float8Ptr(row.MinPrice),
+		MaxPrice:        float8Ptr(row.MaxPrice),
+		Attributes:      decodeAttributes(row.Attributes),
+		AttributeRanges: map[string]validator.AttributeRange{},
+		CreatedAt:       row.CreatedAt.Time,
+	}
+	// Written by Create from a validator.AttributeRange map.
+	_ = json.Unmarshal(row.AttributeRanges, &search.AttributeRanges)
+	return search
+}
+
+func (s *SavedSearchService) Create(ctx context.Context, userID uuid.UUID, params SavedSearchParams) (SavedSearch, error) {
+	count, err := s.db.CountSavedSearchesByUserId(ctx, userID)
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	if count >= MaxSavedSearchesPerUser {
+		return SavedSearch{}, ErrTooManySavedSearches
+	}
+
+	equals := map[string]any{}
+	ranges := map[string]validator.AttributeRange{}
+	if len(params.Attributes) > 0 {
+		if params.CategoryID == nil {
+			return SavedSearch{}, &AttributesError{Problems: validator.Evaluator{
+				"category_id": "filtering by attributes requires a category",
+			}}
+		}
+		schema, err := categorySchema(ctx, s.db, *params.CategoryID)
+		if err != nil {
+			return SavedSearch{}, err
+		}
+		var problems validator.Evaluator
+		equals, ranges, problems = schema.ParseFilters(params.Attributes)
+		if len(problems) > 0 {
+			return SavedSearch{}, &AttributesError{Problems: problems}
+		}
+	}
+
+	encodedEquals, err := json.Marshal(equals)
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	encodedRanges, err := json.Marshal(ranges)
+	if err != nil {
+		return SavedSearch{}, err
+	}
+
+	categoryID := pgtype.UUID{}
+	if params.CategoryID != nil {
+		categoryID = pgtype.UUID{Bytes: *params.CategoryID, Valid: true}
+	}
+
+	row, err := s.db.CreateSavedSearch(ctx, pgstore.CreateSavedSearchParams{
+		UserID:          userID,
+		Name:            params.Name,
+		Query:           params.Query,
+		CategoryID:      categoryID,
+		MinPrice:        float8(params.MinPrice),
+		MaxPrice:        float8(params.MaxPrice),
+		Attributes:      encodedEquals,
+		AttributeRanges: encodedRanges,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return SavedSearch{}, ErrCategoryNotFound
+		}
+		return SavedSearch{}, err
+	}
+
+	return savedSearchFromRow(row), nil
+}
+
+func (s *SavedSearchService) List(ctx context.Context, userID uuid.UUID) ([]SavedSearch, error) {
+	rows, err := s.db.ListSavedSearchesByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	searches := make([]SavedSearch, 0, len(rows))
+	for _, row := range rows {
+		searches = append(searches, savedSearchFromRow(row))
+	}
+
+	return searches, nil
+}
+
+func (s *SavedSearchService) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	deleted, err := s.db.DeleteSavedSearch(ctx, pgstore.DeleteSavedSearchParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrSavedSearchNotFound
+	}
+
+	return nil
+}
+
+// NotifyNewListings checks the products listed since the last call against
+// the saved searches and tells each matching user once per product.
+func (s *SavedSearchService) NotifyNewListings(ctx context.Context) error {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+	products, err := qtx.ClaimUnmatchedProducts(ctx, matchBatchSize)
+	if err != nil {
+		return err
+	}
+
+	var notifications []Notification
+	for _, product := range products {
+		matches, err := qtx.RecordSavedSearchMatches(ctx, product.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, match := range matches {
+			notifications = append(notifications, Notification{
+				UserID:    match.UserID,
+				Kind:      NotificationSavedSearchMatch,
+				ProductID: &product.ID,
+				Title:     fmt.Sprintf("New listing for %q", match.Name),
+				Body:      fmt.Sprintf("%s was just listed.", product.ProductName),
+			})
+		}
+	}
+
+	if err := enqueueNotifications(ctx, qtx, notifications...); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
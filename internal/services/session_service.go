@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionService keeps what the users see of their sessions: where and when
+// they were used. The sessions themselves are kept by scs, the rows here are
+// found by the session token.
+type SessionService struct {
+	pool *pgxpool.Pool
+	db   *pgstore.Queries
+}
+
+func NewSessionService(pool *pgxpool.Pool) SessionService {
+	return SessionService{
+		pool: pool,
+		db:   pgstore.New(pool),
+	}
+}
+
+type UserSession struct {
+	ID         uuid.UUID `json:"id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// The session the request was made with.
+	Current bool `json:"current"`
+}
+
+// Touch records a request of the session, the first one creates it.
+func (s *SessionService) Touch(ctx context.Context, token string, userID uuid.UUID, ip, userAgent string) error {
+	return s.db.TouchUserSession(ctx, pgstore.TouchUserSessionParams{
+		Token:     token,
+		UserID:    userID,
+		Ip:        ip,
+		UserAgent: userAgent,
+	})
+}
+
+// Rename follows a session whose token was renewed.
+func (s *SessionService) Rename(ctx context.Context, token, newToken string) error {
+	return s.db.RenameUserSession(ctx, pgstore.RenameUserSessionParams{
+		NewToken: newToken,
+		Token:    token,
+	})
+}
+
+// Forget drops the sessions with the given tokens, once they are destroyed.
+func (s *SessionService) Forget(ctx context.Context, tokens ...string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	return s.db.DeleteUserSessionsByTokens(ctx, tokens)
+}
+
+// List returns the live sessions of the user, the most recently used first.
+func (s *SessionService) List(ctx context.Context, userID uuid.UUID, currentToken string) ([]UserSession, error) {
+	if err := s.db.DeleteStaleUserSessions(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.ListUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]UserSession, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, UserSession{
+			ID:         row.ID,
+			IP:         row.Ip,
+			UserAgent:  row.UserAgent,
+			CreatedAt:  row.CreatedAt.Time,
+			LastSeenAt: row.LastSeenAt.Time,
+			Current:    row.Token == currentToken,
+		})
+	}
+
+	return sessions, nil
+}
+
+// Tokens returns the tokens of every session of the user, to destroy them.
+func (s *SessionService) Tokens(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return s.db.ListUserSessionTokens(ctx, userID)
+}
+
+// Token returns the token of a session of the user, to destroy it.
+func (s *SessionService) Token(ctx context.Context, userID, id uuid.UUID) (string, error) {
+	token, err := s.db.GetUserSessionToken(ctx, pgstore.GetUserSessionTokenParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrSessionNotFound
+		}
+		return "", err
+	}
+
+	return token, nil
+}
@@ -0,0 +1,339 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+	"github.com/lohanguedes/gobid/internal/totp"
+)
+
+const (
+	recoveryCodeCount = 10
+	// The codes of the step before and after the current one are accepted too.
+	totpSkew = 1
+)
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two factor authentication is already enabled")
+	ErrTwoFactorNotSetUp    = errors.New("start the two factor enrollment first")
+	ErrTwoFactorNotEnabled  = errors.New("two factor authentication is not enabled")
+	ErrInvalidTwoFactorCode = errors.New("the code is invalid or was already used")
+)
+
+// TwoFactorService manages the TOTP codes of the authenticator apps and the
+// recovery codes that replace them when the phone is lost.
+type TwoFactorService struct {
+	pool *pgxpool.Pool
+	db   *pgstore.Queries
+	// Shown by the authenticator apps next to the codes.
+	issuer string
+}
+
+func NewTwoFactorService(pool *pgxpool.Pool, issuer string) TwoFactorService {
+	return TwoFactorService{
+		pool:   pool,
+		db:     pgstore.New(pool),
+		issuer: issuer,
+	}
+}
+
+// TwoFactorSetup is what the authenticator app needs, the URI is usually shown
+// as a QR code and the secret typed when it cannot be scanned.
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type TwoFactorStatus struct {
+	Enabled bool `json:"enabled"`
+	// Only set when enabled.
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+}
+
+func (s *TwoFactorService) Enabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	user, err := s.db.GetUserTotp(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, ErrUserNotFound
+		}
+		return false, err
+	}
+
+	return user.TotpEnabledAt.Valid, nil
+}
+
+func (s *TwoFactorService) Status(ctx context.Context, userID uuid.UUID) (TwoFactorStatus, error) {
+	enabled, err := s.Enabled(ctx, userID)
+	if err != nil || !enabled {
+		return TwoFactorStatus{}, err
+	}
+
+	left, err := s.db.CountUnusedTotpRecoveryCodes(ctx, userID)
+	if err != nil {
+		return TwoFactorStatus{}, err
+	}
+
+	return TwoFactorStatus{Enabled: true, RecoveryCodesLeft: left}, nil
+}
+
+// Setup starts the enrollment with a new secret, the two factor login is
+// only on once Confirm gets a code of it.
+func (s *TwoFactorService) Setup(ctx context.Context, userID uuid.UUID) (TwoFactorSetup, error) {
+	user, err := s.db.GetUserTotp(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return TwoFactorSetup{}, ErrUserNotFound
+		}
+		return TwoFactorSetup{}, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return TwoFactorSetup{}, err
+	}
+
+	updated, err := s.db.SetUserTotpSecret(ctx, pgstore.SetUserTotpSecretParams{
+		ID:         userID,
+		TotpSecret: pgtype.Text{String: secret, Valid: true},
+	})
+	if err != nil {
+		return TwoFactorSetup{}, err
+	}
+	if updated == 0 {
+		return TwoFactorSetup{}, ErrTwoFactorEnabled
+	}
+
+	return TwoFactorSetup{
+		Secret: secret,
+		URI:    totp.URI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm turns the two factor login on with a first code of the secret given
+// by Setup and returns the recovery codes, they are not shown again.
+func (s *TwoFactorService) Confirm(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+	user, err := qtx.GetUserTotp(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.TotpEnabledAt.Valid {
+		return nil, ErrTwoFactorEnabled
+	}
+	if !user.TotpSecret.Valid {
+		return nil, ErrTwoFactorNotSetUp
+	}
+
+	counter, ok := totp.Validate(user.TotpSecret.String, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	err = qtx.EnableUserTotp(ctx, pgstore.EnableUserTotpParams{
+		ID:              userID,
+		TotpLastCounter: counter,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := createRecoveryCodes(ctx, qtx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Verify checks a code of the authenticator app or a recovery code, each one
+// works once.
+func (s *TwoFactorService) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	user, err := s.db.GetUserTotp(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if !user.TotpEnabledAt.Valid {
+		return ErrTwoFactorNotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) != totp.Digits {
+		used, err := s.db.UseTotpRecoveryCode(ctx, pgstore.UseTotpRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(code),
+		})
+		if err != nil {
+			return err
+		}
+		if used == 0 {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	counter, ok := totp.Validate(user.TotpSecret.String, code, time.Now(), totpSkew)
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	// Concurrent logins with the same code race here, only one of them wins.
+	used, err := s.db.UseUserTotpCounter(ctx, pgstore.UseUserTotpCounterParams{
+		ID:              userID,
+		TotpLastCounter: counter,
+	})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+// Login checks the code of the second step of a login. The wrong codes count
+// toward the lockout of the email and the ip like the wrong passwords, and
+// the login is only recorded as succeeded once the code is right.
+func (s *TwoFactorService) Login(ctx context.Context, userID uuid.UUID, code, ip string) error {
+	user, err := s.db.GetUserTotp(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	email := normalizeEmail(user.Email)
+
+	wait, err := loginLockedFor(ctx, s.db, email, ip, time.Now())
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		if err := recordLoginAttempt(ctx, s.db, email, ip, userID, LoginLocked); err != nil {
+			return err
+		}
+		return &LoginLockedError{RetryAfter: wait}
+	}
+
+	if err := s.Verify(ctx, userID, code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			if err := recordLoginAttempt(ctx, s.db, email, ip, userID, LoginFailed); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+
+	return recordLoginAttempt(ctx, s.db, email, ip, userID, LoginSucceeded)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user, the old
+// ones stop working. It needs a valid code like Disable.
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	codes, err := createRecoveryCodes(ctx, s.db.WithTx(tx), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable turns the two factor login off, it needs a valid code so a stolen
+// session cannot do it.
+func (s *TwoFactorService) Disable(ctx context.Context, userID uuid.UUID, code string) error {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.WithTx(tx)
+	if err := qtx.DisableUserTotp(ctx, userID); err != nil {
+		return err
+	}
+	if err := qtx.DeleteTotpRecoveryCodesByUserId(ctx, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// hashRecoveryCode ignores the case and the dash of the codes, the users
+// type them by hand.
+func hashRecoveryCode(code string) []byte {
+	code = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return hashToken(code)
+}
+
+// createRecoveryCodes replaces the recovery codes of the user with new ones,
+// formatted as xxxxx-xxxxx.
+func createRecoveryCodes(ctx context.Context, qtx *pgstore.Queries, userID uuid.UUID) ([]string, error) {
+	if err := qtx.DeleteTotpRecoveryCodesByUserId(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		encoded := strings.ToLower(recoveryCodeEncoding.EncodeToString(raw)[:10])
+		code := encoded[:5] + "-" + encoded[5:]
+
+		err := qtx.CreateTotpRecoveryCode(ctx, pgstore.CreateTotpRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(code),
+		})
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
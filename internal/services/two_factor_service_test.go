@@ -0,0 +1,48 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/lohanguedes/gobid/internal/totp"
+)
+
+func TestHashRecoveryCode(t *testing.T) {
+	want := hashRecoveryCode("abcde-fghij")
+	for _, typed := range []string{"abcde-fghij", "ABCDE-FGHIJ", "abcdefghij", "  AbCdE-fGhIj\n", "abc-de-fghij"} {
+		if got := hashRecoveryCode(typed); !bytes.Equal(got, want) {
+			t.Errorf("%q does not match the code", typed)
+		}
+	}
+	for _, other := range []string{"abcde-fghik", "abcde fghij", "abcdefghi"} {
+		if got := hashRecoveryCode(other); bytes.Equal(got, want) {
+			t.Errorf("%q matches the code", other)
+		}
+	}
+}
+
+// The codes of the steps around now are accepted, for the clocks that drift.
+func TestTotpSkew(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	counter := totp.Counter(now)
+
+	for step := int64(-totpSkew - 1); step <= totpSkew+1; step++ {
+		code, err := totp.Code(secret, counter+step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		matched, ok := totp.Validate(secret, code, now, totpSkew)
+		inWindow := step >= -totpSkew && step <= totpSkew
+		if ok != inWindow {
+			t.Errorf("the code of step %+d: got accepted %t, want %t", step, ok, inWindow)
+		}
+		if ok && matched != counter+step {
+			t.Errorf("the code of step %+d matched the step %d, want %d", step, matched, counter+step)
+		}
+	}
+}
@@ -3,15 +3,31 @@ package services
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/authz"
 	"github.com/lohanguedes/gobid/internal/store/pgstore"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var ErrUserNotFound = errors.New("user not found")
+
+func hashPassword(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), 12)
+}
+
+// normalizeEmail is the form the emails are stored and counted in, so the
+// case variants of an address share the account and its failed logins.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 type UserService struct {
 	// TODO: make this a interface in order to be more idiomatic
 	pool *pgxpool.Pool // do not forget to add the pool of connections here.
@@ -25,20 +41,36 @@ func NewUserService(pool *pgxpool.Pool) UserService {
 	}
 }
 
+func welcomeNotification(userID uuid.UUID) Notification {
+	return Notification{
+		UserID: userID,
+		Kind:   NotificationWelcome,
+		Title:  "Welcome to gobid",
+		Body:   "Your account is ready, start bidding on the live auctions or list your first item.",
+	}
+}
+
 func (us *UserService) CreateUser(ctx context.Context, userName, email, password, bio string) (uuid.UUID, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	hash, err := hashPassword(password)
 	if err != nil {
 		return uuid.UUID{}, err
 	}
 
 	args := pgstore.CreateUserParams{
 		UserName:     userName,
-		Email:        email,
+		Email:        normalizeEmail(email),
 		PasswordHash: hash,
 		Bio:          bio,
 	}
 
-	id, err := us.db.CreateUser(
+	tx, err := us.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := us.db.WithTx(tx)
+	id, err := qtx.CreateUser(
 		ctx,
 		args,
 	)
@@ -47,27 +79,275 @@ func (us *UserService) CreateUser(ctx context.Context, userName, email, password
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
 			return uuid.UUID{}, pgstore.ErrDuplicateEmail
 		}
+		return uuid.UUID{}, err
+	}
+
+	if err := enqueueNotifications(ctx, qtx, welcomeNotification(id)); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.UUID{}, err
 	}
 
 	return id, nil
 }
 
-func (us *UserService) Authenticate(ctx context.Context, email, password string) (uuid.UUID, error) {
+// Authenticate checks the password of the user with the given email, the
+// attempt is recorded with the ip it came from. Once the email or the ip
+// failed too many times it returns a *LoginLockedError without checking the
+// password, unknown emails take as long as the wrong passwords. The users with
+// two factor authentication succeed in TwoFactorService.Login instead.
+func (us *UserService) Authenticate(ctx context.Context, email, password, ip string) (uuid.UUID, error) {
+	email = normalizeEmail(email)
+	wait, err := loginLockedFor(ctx, us.db, email, ip, time.Now())
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	if wait > 0 {
+		if err := recordLoginAttempt(ctx, us.db, email, ip, uuid.Nil, LoginLocked); err != nil {
+			return uuid.UUID{}, err
+		}
+		return uuid.UUID{}, &LoginLockedError{RetryAfter: wait}
+	}
+
 	user, err := us.db.GetUserByEmail(ctx, email)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return uuid.UUID{}, pgstore.ErrInvalidCredentials
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return uuid.UUID{}, err
 		}
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		if err := recordLoginAttempt(ctx, us.db, email, ip, uuid.Nil, LoginFailed); err != nil {
+			return uuid.UUID{}, err
+		}
+		return uuid.UUID{}, pgstore.ErrInvalidCredentials
 	}
 
 	err = bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(password))
 	if err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return uuid.UUID{}, pgstore.ErrInvalidCredentials
+		if !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return uuid.UUID{}, err
+		}
+		if err := recordLoginAttempt(ctx, us.db, email, ip, user.ID, LoginFailed); err != nil {
+			return uuid.UUID{}, err
 		}
+		return uuid.UUID{}, pgstore.ErrInvalidCredentials
+	}
 
+	if err := recordFirstFactor(ctx, us.db, email, ip, user.ID); err != nil {
 		return uuid.UUID{}, err
 	}
 
-	return user.ID, err
+	return user.ID, nil
+}
+
+// ChangePassword sets a new password once the current one is confirmed, the
+// pending reset links stop working.
+func (us *UserService) ChangePassword(ctx context.Context, id uuid.UUID, current, password string) error {
+	user, err := us.db.GetUserById(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(current)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return pgstore.ErrInvalidCredentials
+		}
+		return err
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	tx, err := us.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := us.db.WithTx(tx)
+	if err := setPassword(ctx, qtx, id, hash); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// setPassword changes the password hash, drops the reset tokens of the user
+// and tells them about it.
+func setPassword(ctx context.Context, qtx *pgstore.Queries, userID uuid.UUID, hash []byte) error {
+	err := qtx.UpdateUserPassword(ctx, pgstore.UpdateUserPasswordParams{
+		ID:           userID,
+		PasswordHash: hash,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := qtx.DeletePasswordResetTokensByUserId(ctx, userID); err != nil {
+		return err
+	}
+
+	return enqueueNotifications(ctx, qtx, Notification{
+		UserID: userID,
+		Kind:   NotificationPasswordChanged,
+		Title:  "Your password was changed",
+		Body:   "The password of your account was just changed and your other sessions were logged out. If it was not you, reset your password right away.",
+	})
+}
+
+func (us *UserService) IsEmailVerified(ctx context.Context, id uuid.UUID) (bool, error) {
+	verified, err := us.db.IsEmailVerified(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return verified, nil
+}
+
+// Role returns the role of the user, the missing users have none.
+func (us *UserService) Role(ctx context.Context, id uuid.UUID) (authz.Role, error) {
+	subject, err := loadSubject(ctx, us.db, id)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return subject.Role, nil
+}
+
+var ErrCannotAssignRole = errors.New("only the admins may change the roles, and not their own")
+
+// SetRole gives the user the role, for the admins.
+func (us *UserService) SetRole(ctx context.Context, actorID, id uuid.UUID, role authz.Role) error {
+	actor, err := loadSubject(ctx, us.db, actorID)
+	if err != nil {
+		return err
+	}
+	if !authz.CanAssignRole(actor, id, role) {
+		return ErrCannotAssignRole
+	}
+
+	updated, err := us.db.SetUserRole(ctx, pgstore.SetUserRoleParams{
+		ID:   id,
+		Role: string(role),
+	})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// BecomeSeller lets the user list products, it needs a verified email. The
+// moderators and the admins can sell already and keep their role.
+func (us *UserService) BecomeSeller(ctx context.Context, id uuid.UUID) error {
+	if err := checkEmailVerified(ctx, us.db, id); err != nil {
+		return err
+	}
+
+	return us.db.PromoteUserToSeller(ctx, id)
+}
+
+// Account is the user as they see themselves.
+type Account struct {
+	ID        uuid.UUID `json:"id"`
+	UserName  string    `json:"user_name"`
+	Email     string    `json:"email"`
+	Bio       string    `json:"bio"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Bidding and selling need a verified email.
+	EmailVerified bool `json:"email_verified"`
+	// Selling needs the seller role or one above it.
+	Role authz.Role `json:"role"`
+}
+
+// PublicProfile is what anyone may see of a user, it never has the email.
+type PublicProfile struct {
+	ID          uuid.UUID `json:"id"`
+	UserName    string    `json:"user_name"`
+	Bio         string    `json:"bio"`
+	MemberSince time.Time `json:"member_since"`
+}
+
+func (us *UserService) Account(ctx context.Context, id uuid.UUID) (Account, error) {
+	user, err := us.db.GetUserById(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Account{}, ErrUserNotFound
+		}
+		return Account{}, err
+	}
+
+	return Account{
+		ID:            user.ID,
+		UserName:      user.UserName,
+		Email:         user.Email,
+		Bio:           user.Bio,
+		CreatedAt:     user.CreatedAt.Time,
+		UpdatedAt:     user.UpdatedAt.Time,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		Role:          authz.Role(user.Role),
+	}, nil
+}
+
+// UpdateAccount changes the fields that are not nil.
+func (us *UserService) UpdateAccount(ctx context.Context, id uuid.UUID, userName, bio *string) (Account, error) {
+	params := pgstore.UpdateUserParams{ID: id}
+	if userName != nil {
+		params.UserName = pgtype.Text{String: *userName, Valid: true}
+	}
+	if bio != nil {
+		params.Bio = pgtype.Text{String: *bio, Valid: true}
+	}
+
+	user, err := us.db.UpdateUser(ctx, params)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Account{}, ErrUserNotFound
+		}
+		return Account{}, err
+	}
+
+	return Account{
+		ID:            user.ID,
+		UserName:      user.UserName,
+		Email:         user.Email,
+		Bio:           user.Bio,
+		CreatedAt:     user.CreatedAt.Time,
+		UpdatedAt:     user.UpdatedAt.Time,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		Role:          authz.Role(user.Role),
+	}, nil
+}
+
+func (us *UserService) PublicProfile(ctx context.Context, id uuid.UUID) (PublicProfile, error) {
+	user, err := us.db.GetUserById(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return PublicProfile{}, ErrUserNotFound
+		}
+		return PublicProfile{}, err
+	}
+
+	return PublicProfile{
+		ID:          user.ID,
+		UserName:    user.UserName,
+		Bio:         user.Bio,
+		MemberSince: user.CreatedAt.Time,
+	}, nil
 }
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+)
+
+type WatchlistService struct {
+	pool *pgxpool.Pool
+	db   *pgstore.Queries
+}
+
+func NewWatchlistService(pool *pgxpool.Pool) WatchlistService {
+	return WatchlistService{
+		pool: pool,
+		db:   pgstore.New(pool),
+	}
+}
+
+// WatchedProduct is a product of the watchlist of a user.
+type WatchedProduct struct {
+	ProductListing
+	WatchedAt time.Time `json:"watched_at"`
+}
+
+// Watch adds the product to the watchlist of the user, watching it twice is not an error.
+func (s *WatchlistService) Watch(ctx context.Context, userID, productID uuid.UUID) error {
+	err := s.db.AddToWatchlist(ctx, pgstore.AddToWatchlistParams{
+		UserID:    userID,
+		ProductID: productID,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrProductNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *WatchlistService) Unwatch(ctx context.Context, userID, productID uuid.UUID) error {
+	return s.db.RemoveFromWatchlist(ctx, pgstore.RemoveFromWatchlistParams{
+		UserID:    userID,
+		ProductID: productID,
+	})
+}
+
+// Watchlist returns the products watched by the user, ending first.
+func (s *WatchlistService) Watchlist(ctx context.Context, userID uuid.UUID) ([]WatchedProduct, error) {
+	rows, err := s.db.ListWatchlistByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	watched := make([]WatchedProduct, 0, len(rows))
+	for _, row := range rows {
+		product := ProductData{
+			ID:           row.ID,
+			SellerID:     row.SellerID,
+			ProductName:  row.ProductName,
+			Description:  row.Description,
+			BasePrice:    row.BasePrice,
+			AuctionStart: row.AuctionStart.Time,
+			AuctionEnd:   row.AuctionEnd.Time,
+			IsSold:       row.IsSold,
+			CancelledAt:  timePtr(row.CancelledAt),
+			CategoryID:   uuidPtr(row.CategoryID),
+			Attributes:   decodeAttributes(row.Attributes),
+		}
+		watched = append(watched, WatchedProduct{
+			ProductListing: ProductListing{
+				ProductData: product,
+				Status:      auctionStatus(product, now),
+				HighestBid:  row.HighestBid,
+				BidCount:    row.BidCount,
+			},
+			WatchedAt: row.WatchedAt.Time,
+		})
+	}
+
+	return watched, nil
+}
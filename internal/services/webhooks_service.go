@@ -0,0 +1,439 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/gobid/internal/store/pgstore"
+)
+
+// The events sellers can subscribe to, they are about the auctions of the
+// owner of the endpoint.
+const (
+	WebhookBidPlaced    = "bid.placed"
+	WebhookAuctionEnded = "auction.ended"
+	WebhookItemSold     = "item.sold"
+	// Only sent by SendTest.
+	WebhookTest = "webhook.test"
+)
+
+const (
+	OutboxConsumerWebhook = "webhook"
+
+	MaxWebhookEndpointsPerUser = 10
+
+	webhookTimeout       = 10 * time.Second
+	webhookDeliveriesLog = 50
+)
+
+var (
+	ErrWebhookNotFound          = errors.New("webhook endpoint not found")
+	ErrTooManyWebhooks          = errors.New("too many webhook endpoints, delete one first")
+	ErrWebhookAddressNotAllowed = errors.New("webhook address is not allowed")
+)
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, private too
+// although net.IP.IsPrivate does not say so.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+type WebhookService struct {
+	pool   *pgxpool.Pool
+	db     *pgstore.Queries
+	client *http.Client
+}
+
+// NewWebhookService refuses to call private and loopback addresses unless
+// allowPrivate, the endpoints are given by the users. allowPrivate is for
+// development only, it lets them reach the internal network.
+func NewWebhookService(pool *pgxpool.Pool, allowPrivate bool) WebhookService {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		dialer.Control = refusePrivateAddresses
+	}
+
+	return WebhookService{
+		pool: pool,
+		db:   pgstore.New(pool),
+		client: &http.Client{
+			Timeout:   webhookTimeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+			// A redirect would be followed without the signature checks of the receiver.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// refusePrivateAddresses is the dialer Control of the webhooks, it is checked
+// on the resolved address so DNS can not point the endpoint elsewhere later.
+func refusePrivateAddresses(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || sharedAddressSpace.Contains(ip) {
+		return ErrWebhookAddressNotAllowed
+	}
+	return nil
+}
+
+type WebhookEndpoint struct {
+	ID     uuid.UUID `json:"id"`
+	URL    string    `json:"url"`
+	Events []string  `json:"events"`
+	// Only set when the endpoint is created.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func webhookEndpointFromRow(row pgstore.WebhookEndpoint) WebhookEndpoint {
+	return WebhookEndpoint{
+		ID:        row.ID,
+		URL:       row.Url,
+		Events:    row.Events,
+		CreatedAt: row.CreatedAt.Time,
+	}
+}
+
+type WebhookDelivery struct {
+	ID         uuid.UUID       `json:"id"`
+	DeliveryID uuid.UUID       `json:"delivery_id"`
+	EventType  string          `json:"event_type"`
+	Payload    json.RawMessage `json:"payload"`
+	StatusCode *int32          `json:"status_code"`
+	Error      string          `json:"error,omitempty"`
+	DurationMs int32           `json:"duration_ms"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+func webhookDeliveryFromRow(row pgstore.WebhookDelivery) WebhookDelivery {
+	delivery := WebhookDelivery{
+		ID:         row.ID,
+		DeliveryID: row.DeliveryID,
+		EventType:  row.EventType,
+		Payload:    row.Payload,
+		Error:      row.Error.String,
+		DurationMs: row.DurationMs,
+		CreatedAt:  row.CreatedAt.Time,
+	}
+	if row.StatusCode.Valid {
+		delivery.StatusCode = &row.StatusCode.Int32
+	}
+	return delivery
+}
+
+// WebhookEvent is the body of the requests.
+type WebhookEvent struct {
+	// The same for every endpoint the event is sent to.
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+type BidPlacedEvent struct {
+	ProductID   uuid.UUID `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	BidID       uuid.UUID `json:"bid_id"`
+	BidderID    uuid.UUID `json:"bidder_id"`
+	Amount      float64   `json:"amount"`
+	PlacedAt    time.Time `json:"placed_at"`
+}
+
+type AuctionEndedEvent struct {
+	ProductID   uuid.UUID  `json:"product_id"`
+	ProductName string     `json:"product_name"`
+	Sold        bool       `json:"sold"`
+	WinnerID    *uuid.UUID `json:"winner_id,omitempty"`
+	WinningBid  *float64   `json:"winning_bid,omitempty"`
+	EndedAt     time.Time  `json:"ended_at"`
+}
+
+type ItemSoldEvent struct {
+	ProductID   uuid.UUID `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	BuyerID     uuid.UUID `json:"buyer_id"`
+	Amount      float64   `json:"amount"`
+}
+
+// webhookOutboxPayload is what enqueueWebhookEvent writes for each endpoint.
+type webhookOutboxPayload struct {
+	EndpointID uuid.UUID       `json:"endpoint_id"`
+	Event      json.RawMessage `json:"event"`
+}
+
+// enqueueWebhookEvent writes the event to the outbox once per endpoint of
+// the user subscribed to it, so each endpoint is retried on its own.
+func enqueueWebhookEvent(ctx context.Context, qtx *pgstore.Queries, userID uuid.UUID, eventType string, data any) error {
+	endpoints, err := qtx.ListWebhookEndpointIdsForEvent(ctx, pgstore.ListWebhookEndpointIdsForEventParams{
+		UserID:    userID,
+		EventType: eventType,
+	})
+	if err != nil || len(endpoints) == 0 {
+		return err
+	}
+
+	event, err := json.Marshal(WebhookEvent{
+		ID:        uuid.New(),
+		Type:      eventType,
+		CreatedAt: time.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, endpointID := range endpoints {
+		payload, err := json.Marshal(webhookOutboxPayload{EndpointID: endpointID, Event: event})
+		if err != nil {
+			return err
+		}
+		err = qtx.EnqueueOutboxEvent(ctx, pgstore.EnqueueOutboxEventParams{
+			EventID:  uuid.New(),
+			Consumer: OutboxConsumerWebhook,
+			Payload:  payload,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *WebhookService) Create(ctx context.Context, userID uuid.UUID, url string, events []string) (WebhookEndpoint, error) {
+	count, err := s.db.CountWebhookEndpointsByUserId(ctx, userID)
+	if err != nil {
+		return WebhookEndpoint{}, err
+	}
+	if count >= MaxWebhookEndpointsPerUser {
+		return WebhookEndpoint{}, ErrTooManyWebhooks
+	}
+
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return WebhookEndpoint{}, err
+	}
+	secret := "whsec_" + hex.EncodeToString(raw)
+
+	row, err := s.db.CreateWebhookEndpoint(ctx, pgstore.CreateWebhookEndpointParams{
+		UserID: userID,
+		Url:    url,
+		Secret: secret,
+		Events: events,
+	})
+	if err != nil {
+		return WebhookEndpoint{}, err
+	}
+
+	endpoint := webhookEndpointFromRow(row)
+	endpoint.Secret = row.Secret
+	return endpoint, nil
+}
+
+func (s *WebhookService) List(ctx context.Context, userID uuid.UUID) ([]WebhookEndpoint, error) {
+	rows, err := s.db.ListWebhookEndpointsByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make([]WebhookEndpoint, 0, len(rows))
+	for _, row := range rows {
+		endpoints = append(endpoints, webhookEndpointFromRow(row))
+	}
+
+	return endpoints, nil
+}
+
+// Delete also drops the deliveries still waiting in the outbox, they are skipped.
+func (s *WebhookService) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	deleted, err := s.db.DeleteWebhookEndpoint(ctx, pgstore.DeleteWebhookEndpointParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// endpoint returns the endpoint if the user owns it.
+func (s *WebhookService) endpoint(ctx context.Context, userID, id uuid.UUID) (pgstore.WebhookEndpoint, error) {
+	row, err := s.db.GetWebhookEndpointById(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgstore.WebhookEndpoint{}, ErrWebhookNotFound
+		}
+		return pgstore.WebhookEndpoint{}, err
+	}
+	if row.UserID != userID {
+		return pgstore.WebhookEndpoint{}, ErrWebhookNotFound
+	}
+
+	return row, nil
+}
+
+// Deliveries returns the latest delivery attempts to the endpoint.
+func (s *WebhookService) Deliveries(ctx context.Context, userID, id uuid.UUID) ([]WebhookDelivery, error) {
+	if _, err := s.endpoint(ctx, userID, id); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.ListWebhookDeliveriesByEndpointId(ctx, pgstore.ListWebhookDeliveriesByEndpointIdParams{
+		EndpointID: id,
+		Limit:      webhookDeliveriesLog,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]WebhookDelivery, 0, len(rows))
+	for _, row := range rows {
+		deliveries = append(deliveries, webhookDeliveryFromRow(row))
+	}
+
+	return deliveries, nil
+}
+
+// SendTest sends a webhook.test event to the endpoint right away, it is not retried.
+func (s *WebhookService) SendTest(ctx context.Context, userID, id uuid.UUID) (WebhookDelivery, error) {
+	endpoint, err := s.endpoint(ctx, userID, id)
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+
+	event, err := json.Marshal(WebhookEvent{
+		ID:        uuid.New(),
+		Type:      WebhookTest,
+		CreatedAt: time.Now(),
+		Data:      map[string]any{"endpoint_id": endpoint.ID},
+	})
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+
+	return s.send(ctx, endpoint, uuid.New(), WebhookTest, event)
+}
+
+// Deliver is the outbox consumer of the webhooks, deliveryID is sent as the
+// X-Gobid-Delivery header so receivers can skip the redeliveries.
+func (s *WebhookService) Deliver(ctx context.Context, deliveryID uuid.UUID, payload []byte) error {
+	var p webhookOutboxPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+	var event WebhookEvent
+	if err := json.Unmarshal(p.Event, &event); err != nil {
+		return err
+	}
+
+	endpoint, err := s.db.GetWebhookEndpointById(ctx, p.EndpointID)
+	if err != nil {
+		// The endpoint was deleted.
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	delivery, err := s.send(ctx, endpoint, deliveryID, event.Type, p.Event)
+	if err != nil {
+		return err
+	}
+	if delivery.Error != "" {
+		return errors.New(delivery.Error)
+	}
+
+	return nil
+}
+
+// send posts the event to the endpoint and logs the attempt, a failed
+// delivery is reported in the Error of the returned log entry.
+func (s *WebhookService) send(ctx context.Context, endpoint pgstore.WebhookEndpoint, deliveryID uuid.UUID, eventType string, body []byte) (WebhookDelivery, error) {
+	start := time.Now()
+	statusCode := pgtype.Int4{}
+
+	req, sendErr := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.Url, bytes.NewReader(body))
+	if sendErr == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "gobid-webhooks")
+		req.Header.Set("X-Gobid-Event", eventType)
+		req.Header.Set("X-Gobid-Delivery", deliveryID.String())
+		req.Header.Set("X-Gobid-Signature", SignWebhook(endpoint.Secret, start, body))
+
+		var res *http.Response
+		res, sendErr = s.client.Do(req)
+		if sendErr == nil {
+			res.Body.Close()
+			statusCode = pgtype.Int4{Int32: int32(res.StatusCode), Valid: true}
+			if res.StatusCode < 200 || res.StatusCode > 299 {
+				sendErr = fmt.Errorf("webhook endpoint answered %d", res.StatusCode)
+			}
+		}
+	}
+
+	errText := pgtype.Text{}
+	if sendErr != nil {
+		errText = pgtype.Text{String: sendErr.Error(), Valid: true}
+	}
+
+	row, err := s.db.CreateWebhookDelivery(ctx, pgstore.CreateWebhookDeliveryParams{
+		EndpointID: endpoint.ID,
+		DeliveryID: deliveryID,
+		EventType:  eventType,
+		Payload:    body,
+		StatusCode: statusCode,
+		Error:      errText,
+		DurationMs: int32(time.Since(start).Milliseconds()),
+	})
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+	// Only the log of the latest attempts is kept.
+	err = s.db.PruneWebhookDeliveries(ctx, pgstore.PruneWebhookDeliveriesParams{
+		EndpointID: endpoint.ID,
+		Keep:       webhookDeliveriesLog,
+	})
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+
+	return webhookDeliveryFromRow(row), nil
+}
+
+// SignWebhook returns the X-Gobid-Signature header: "t=<unix time>,v1=<hex>"
+// where v1 is the HMAC-SHA256 of "<unix time>.<body>" keyed by the secret of
+// the endpoint. Receivers should also reject old timestamps.
+func SignWebhook(secret string, t time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// The receivers check this exact format, a change breaks all of them.
+func TestSignWebhook(t *testing.T) {
+	body := []byte(`{"id":"evt_1","type":"bid.placed"}`)
+	got := SignWebhook("whsec_test", time.Unix(1700000000, 0), body)
+
+	want := "t=1700000000,v1=c22c577280f3986ae355d05cc00ce5824ea377118ca21d14b95170cc8eb2657b"
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestRefusePrivateAddresses(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"127.0.0.1:80", false},
+		{"[::1]:443", false},
+		{"10.0.0.1:80", false},
+		{"172.16.5.4:80", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false},
+		{"100.64.0.1:80", false},
+		{"100.127.255.254:80", false},
+		{"0.0.0.0:80", false},
+		{"[fd00::1]:443", false},
+		{"[fe80::1]:443", false},
+		{"[::ffff:127.0.0.1]:80", false},
+		{"93.184.216.34:443", true},
+		{"100.128.0.1:443", true},
+		{"[2606:4700:4700::1111]:443", true},
+	}
+
+	for _, tt := range tests {
+		err := refusePrivateAddresses("tcp", tt.address, nil)
+		if tt.allowed && err != nil {
+			t.Errorf("%s: got %v, want it allowed", tt.address, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrWebhookAddressNotAllowed) {
+			t.Errorf("%s: got %v, want ErrWebhookAddressNotAllowed", tt.address, err)
+		}
+	}
+}
+
+func TestWebhookClientRefusesLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	guarded := NewWebhookService(nil, false)
+	if _, err := guarded.client.Get(srv.URL); !errors.Is(err, ErrWebhookAddressNotAllowed) {
+		t.Fatalf("got %v, want ErrWebhookAddressNotAllowed", err)
+	}
+
+	// Only for development.
+	open := NewWebhookService(nil, true)
+	res, err := open.client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("got %v with the private addresses allowed", err)
+	}
+	res.Body.Close()
+}
@@ -0,0 +1,20 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrInvalidKey = errors.New("blobstore: invalid key")
+
+// BlobStore keeps binary objects, like product images, by key. The local disk
+// implementation is used in development, an S3 compatible one only has to
+// satisfy this interface.
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	// Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL is where clients download the object from.
+	URL(key string) string
+}
@@ -0,0 +1,115 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// testBlobStore checks what the services expect of every BlobStore, the
+// objects it stores must be downloadable at their URL with get.
+func testBlobStore(t *testing.T, store BlobStore, get func(url string) (*http.Response, error)) {
+	t.Helper()
+	ctx := context.Background()
+
+	download := func(key string) (int, []byte) {
+		t.Helper()
+		res, err := get(store.URL(key))
+		if err != nil {
+			t.Fatalf("GET %s: %v", store.URL(key), err)
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatalf("reading %s: %v", store.URL(key), err)
+		}
+		return res.StatusCode, body
+	}
+
+	t.Run("put then download", func(t *testing.T) {
+		key := "products/1/original.png"
+		if err := store.Put(ctx, key, strings.NewReader("first"), "image/png"); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		if status, body := download(key); status != http.StatusOK || string(body) != "first" {
+			t.Fatalf("got %d %q, want 200 %q", status, body, "first")
+		}
+	})
+
+	t.Run("put replaces", func(t *testing.T) {
+		key := "products/2/original.png"
+		for _, content := range []string{"old", "new"} {
+			if err := store.Put(ctx, key, strings.NewReader(content), "image/png"); err != nil {
+				t.Fatalf("Put %q: %v", content, err)
+			}
+		}
+		if status, body := download(key); status != http.StatusOK || string(body) != "new" {
+			t.Fatalf("got %d %q, want 200 %q", status, body, "new")
+		}
+	})
+
+	t.Run("failed put", func(t *testing.T) {
+		key := "products/3/original.png"
+		if err := store.Put(ctx, key, strings.NewReader("kept"), "image/png"); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		broken := io.MultiReader(strings.NewReader("half"), errReader{})
+		if err := store.Put(ctx, key, broken, "image/png"); err == nil {
+			t.Fatal("Put of a failing body succeeded")
+		}
+		// Readers never see a half written object.
+		if status, body := download(key); status != http.StatusOK || string(body) != "kept" {
+			t.Fatalf("got %d %q, want 200 %q", status, body, "kept")
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		key := "products/4/thumb.jpg"
+		if err := store.Put(ctx, key, bytes.NewReader([]byte{0xff, 0xd8}), "image/jpeg"); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		if err := store.Delete(ctx, key); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if status, _ := download(key); status != http.StatusNotFound {
+			t.Fatalf("got %d after Delete, want 404", status)
+		}
+		if err := store.Delete(ctx, key); err != nil {
+			t.Fatalf("Delete of a missing key: %v", err)
+		}
+	})
+
+	t.Run("only objects are served", func(t *testing.T) {
+		if err := store.Put(ctx, "products/5/original.png", strings.NewReader("listed"), "image/png"); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		// The directories would list the keys, the temporary files of an upload
+		// in progress are half written.
+		for _, key := range []string{"products", "products/", "products/5/", "products/5/.upload-123456"} {
+			if status, body := download(key); status != http.StatusNotFound {
+				t.Errorf("got %d %q for %s, want 404", status, body, store.URL(key))
+			}
+		}
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		for _, key := range []string{"", "/", "../escape", "products/../../escape", "products//double", "/absolute", "products/.upload-1", ".hidden/original.png"} {
+			if err := store.Put(ctx, key, strings.NewReader("x"), "text/plain"); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Put(%q) = %v, want ErrInvalidKey", key, err)
+			}
+			if err := store.Delete(ctx, key); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Delete(%q) = %v, want ErrInvalidKey", key, err)
+			}
+		}
+	})
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
@@ -0,0 +1,50 @@
+package blobstore
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	root := t.TempDir()
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	store, err := NewLocalStore(root, srv.URL+"/media/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.Handle("/media/", http.StripPrefix("/media", store.Handler()))
+
+	// An upload in progress, Handler must not serve it.
+	upload := filepath.Join(root, "products", "5", ".upload-123456")
+	if err := os.MkdirAll(filepath.Dir(upload), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(upload, []byte("half"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	testBlobStore(t, store, srv.Client().Get)
+
+	if err := os.Remove(upload); err != nil {
+		t.Fatal(err)
+	}
+	// The failed uploads leave no temporary files behind.
+	err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Base(path)[0] == '.' {
+			t.Errorf("leftover temporary file %s", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -37,6 +37,26 @@ func (q *Queries) CreateBid(ctx context.Context, arg CreateBidParams) (Bid, erro
 	return i, err
 }
 
+const getBidStatsByProductId = `-- name: GetBidStatsByProductId :one
+SELECT
+    COALESCE(MAX(bid_amount), 0)::float AS highest_bid,
+    COUNT(*) AS bid_count
+FROM bids
+WHERE product_id = $1
+`
+
+type GetBidStatsByProductIdRow struct {
+	HighestBid float64 `json:"highest_bid"`
+	BidCount   int64   `json:"bid_count"`
+}
+
+func (q *Queries) GetBidStatsByProductId(ctx context.Context, productID uuid.UUID) (GetBidStatsByProductIdRow, error) {
+	row := q.db.QueryRow(ctx, getBidStatsByProductId, productID)
+	var i GetBidStatsByProductIdRow
+	err := row.Scan(&i.HighestBid, &i.BidCount)
+	return i, err
+}
+
 const getBidsByProductId = `-- name: GetBidsByProductId :many
 SELECT id, product_id, bidder_id, bid_amount, created_at FROM bids
 WHERE product_id = $1
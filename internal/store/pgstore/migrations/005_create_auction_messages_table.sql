@@ -0,0 +1,28 @@
+-- Write your migrate up statements here
+--
+CREATE TABLE IF NOT EXISTS auction_messages (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES products (id),
+    sender_id UUID NOT NULL REFERENCES users (id),
+
+    kind TEXT NOT NULL CHECK (kind IN ('chat', 'answer')),
+    body TEXT NOT NULL,
+    -- Seller answers point to the question they are answering.
+    reply_to UUID REFERENCES auction_messages (id),
+
+    -- Flagged messages are rejected, they are kept as a record and never shown in the room.
+    is_flagged BOOLEAN NOT NULL DEFAULT false,
+    flag_reason TEXT,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX auction_messages_product_id_created_at_idx ON auction_messages (product_id, created_at DESC);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS auction_messages_product_id_created_at_idx;
+DROP TABLE IF EXISTS auction_messages;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
@@ -0,0 +1,14 @@
+-- Write your migrate up statements here
+--
+ALTER TABLE products
+    ADD COLUMN cancelled_at TIMESTAMPTZ,
+    ADD COLUMN relisted_from UUID REFERENCES products (id);
+
+---- create above / drop below ----
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS relisted_from,
+    DROP COLUMN IF EXISTS cancelled_at;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
@@ -0,0 +1,25 @@
+-- Write your migrate up statements here
+--
+CREATE TABLE IF NOT EXISTS product_images (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+
+    -- Blob store key of the original image, thumbnails are stored next to it.
+    storage_key TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX product_images_product_id_idx ON product_images (product_id, position);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS product_images_product_id_idx;
+DROP TABLE IF EXISTS product_images;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
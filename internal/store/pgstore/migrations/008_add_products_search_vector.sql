@@ -0,0 +1,17 @@
+-- Write your migrate up statements here
+--
+ALTER TABLE products
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(product_name, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'B')
+    ) STORED;
+
+CREATE INDEX products_search_vector_idx ON products USING GIN (search_vector);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS products_search_vector_idx;
+ALTER TABLE products DROP COLUMN IF EXISTS search_vector;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
@@ -0,0 +1,34 @@
+-- Write your migrate up statements here
+--
+CREATE TABLE IF NOT EXISTS categories (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- Root categories have no parent.
+    parent_id UUID REFERENCES categories (id),
+
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) UNIQUE NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX categories_parent_id_idx ON categories (parent_id);
+
+-- Products created before categories existed keep a NULL category.
+ALTER TABLE products ADD COLUMN category_id UUID REFERENCES categories (id);
+CREATE INDEX products_category_id_idx ON products (category_id);
+
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT false;
+
+---- create above / drop below ----
+
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
+
+DROP INDEX IF EXISTS products_category_id_idx;
+ALTER TABLE products DROP COLUMN IF EXISTS category_id;
+
+DROP INDEX IF EXISTS categories_parent_id_idx;
+DROP TABLE IF EXISTS categories;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
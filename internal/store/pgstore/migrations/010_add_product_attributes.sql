@@ -0,0 +1,17 @@
+-- Write your migrate up statements here
+--
+-- A list of attribute definitions, see validator.AttributeSchema.
+ALTER TABLE categories ADD COLUMN attribute_schema JSONB NOT NULL DEFAULT '[]';
+
+-- The attribute values of the product, keyed by attribute name.
+ALTER TABLE products ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';
+CREATE INDEX products_attributes_idx ON products USING GIN (attributes jsonb_path_ops);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS products_attributes_idx;
+ALTER TABLE products DROP COLUMN IF EXISTS attributes;
+ALTER TABLE categories DROP COLUMN IF EXISTS attribute_schema;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
@@ -0,0 +1,36 @@
+-- Write your migrate up statements here
+--
+CREATE TABLE IF NOT EXISTS watchlist (
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (user_id, product_id)
+);
+
+CREATE INDEX watchlist_product_id_idx ON watchlist (product_id);
+
+-- Set once the watchers were told the auction ends soon, cleared when the end moves.
+ALTER TABLE products ADD COLUMN ending_soon_notified_at TIMESTAMPTZ;
+-- Set once the ended auction was settled: is_sold decided and watchers told.
+ALTER TABLE products ADD COLUMN settled_at TIMESTAMPTZ;
+-- The auctions that ended before were settled back then, the scheduler must
+-- not tell their winners and sellers again.
+UPDATE products SET settled_at = auction_end WHERE auction_end <= now();
+
+-- The auctions the scheduler settles, see ListUnsettledAuctions.
+CREATE INDEX products_unsettled_auction_end_idx ON products (auction_end)
+WHERE settled_at IS NULL AND cancelled_at IS NULL;
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS products_unsettled_auction_end_idx;
+ALTER TABLE products DROP COLUMN IF EXISTS settled_at;
+ALTER TABLE products DROP COLUMN IF EXISTS ending_soon_notified_at;
+
+DROP INDEX IF EXISTS watchlist_product_id_idx;
+DROP TABLE IF EXISTS watchlist;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
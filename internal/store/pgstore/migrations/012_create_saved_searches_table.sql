@@ -0,0 +1,49 @@
+-- Write your migrate up statements here
+--
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+
+    name VARCHAR(255) NOT NULL,
+    -- Same filters as the product search, an empty query matches every product.
+    query TEXT NOT NULL DEFAULT '',
+    category_id UUID REFERENCES categories (id) ON DELETE CASCADE,
+    min_price FLOAT,
+    max_price FLOAT,
+    attributes JSONB NOT NULL DEFAULT '{}',
+    attribute_ranges JSONB NOT NULL DEFAULT '{}',
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX saved_searches_user_id_idx ON saved_searches (user_id);
+
+-- A user is told about a product once, even when several of their searches match it.
+CREATE TABLE IF NOT EXISTS saved_search_matches (
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    saved_search_id UUID REFERENCES saved_searches (id) ON DELETE SET NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (user_id, product_id)
+);
+
+-- Set once the product was checked against the saved searches. The products
+-- listed before saved searches existed are not checked.
+ALTER TABLE products ADD COLUMN saved_searches_matched_at TIMESTAMPTZ;
+UPDATE products SET saved_searches_matched_at = created_at;
+CREATE INDEX products_saved_searches_unmatched_idx ON products (created_at)
+    WHERE saved_searches_matched_at IS NULL;
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS products_saved_searches_unmatched_idx;
+ALTER TABLE products DROP COLUMN IF EXISTS saved_searches_matched_at;
+
+DROP TABLE IF EXISTS saved_search_matches;
+DROP INDEX IF EXISTS saved_searches_user_id_idx;
+DROP TABLE IF EXISTS saved_searches;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
@@ -0,0 +1,27 @@
+-- Write your migrate up statements here
+--
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+
+    kind VARCHAR(64) NOT NULL,
+    -- Set when the notification is about an auction.
+    product_id UUID REFERENCES products (id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+
+    read_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX notifications_user_id_created_at_idx ON notifications (user_id, created_at DESC, id DESC);
+CREATE INDEX notifications_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS notifications_unread_idx;
+DROP INDEX IF EXISTS notifications_user_id_created_at_idx;
+DROP TABLE IF EXISTS notifications;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
@@ -0,0 +1,18 @@
+-- Write your migrate up statements here
+--
+-- A missing row means the default, which is to send the email.
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    kind VARCHAR(64) NOT NULL,
+    email BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (user_id, kind)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS notification_preferences;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
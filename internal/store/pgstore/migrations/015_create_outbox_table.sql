@@ -0,0 +1,37 @@
+-- Write your migrate up statements here
+--
+-- The side effects of a change, written in the same transaction. An event
+-- has one row per consumer, so each one is retried on its own.
+CREATE TABLE IF NOT EXISTS outbox (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- The same for every consumer of the event, they use it to tell redeliveries apart.
+    event_id UUID NOT NULL,
+    consumer VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error TEXT,
+    delivered_at TIMESTAMPTZ,
+    -- Set when the event ran out of attempts, it is kept for inspection.
+    dead_at TIMESTAMPTZ,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    UNIQUE (event_id, consumer)
+);
+
+CREATE INDEX outbox_pending_idx ON outbox (next_attempt_at)
+WHERE delivered_at IS NULL AND dead_at IS NULL;
+
+CREATE INDEX outbox_dead_idx ON outbox (dead_at DESC)
+WHERE dead_at IS NOT NULL;
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS outbox_dead_idx;
+DROP INDEX IF EXISTS outbox_pending_idx;
+DROP TABLE IF EXISTS outbox;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
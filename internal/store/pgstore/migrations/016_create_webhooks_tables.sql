@@ -0,0 +1,44 @@
+-- Write your migrate up statements here
+--
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+
+    url TEXT NOT NULL,
+    -- Signs the payloads, the receiver gets it once when the endpoint is created.
+    secret VARCHAR(64) NOT NULL,
+    events TEXT[] NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX webhook_endpoints_user_id_idx ON webhook_endpoints (user_id);
+
+-- One row per attempt.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    endpoint_id UUID NOT NULL REFERENCES webhook_endpoints (id) ON DELETE CASCADE,
+    -- Sent as the X-Gobid-Delivery header, the same on retries.
+    delivery_id UUID NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+
+    -- NULL when there was no response.
+    status_code INTEGER,
+    error TEXT,
+    duration_ms INTEGER NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX webhook_deliveries_endpoint_id_created_at_idx ON webhook_deliveries (endpoint_id, created_at DESC);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS webhook_deliveries_endpoint_id_created_at_idx;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP INDEX IF EXISTS webhook_endpoints_user_id_idx;
+DROP TABLE IF EXISTS webhook_endpoints;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
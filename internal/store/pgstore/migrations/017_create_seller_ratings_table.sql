@@ -0,0 +1,23 @@
+-- Write your migrate up statements here
+--
+-- The buyer of a sold auction rates its seller once.
+CREATE TABLE IF NOT EXISTS seller_ratings (
+    product_id UUID PRIMARY KEY REFERENCES products (id) ON DELETE CASCADE,
+    seller_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    buyer_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+
+    score INTEGER NOT NULL CHECK (score BETWEEN 1 AND 5),
+    comment TEXT NOT NULL DEFAULT '',
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX seller_ratings_seller_id_created_at_idx ON seller_ratings (seller_id, created_at DESC);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS seller_ratings_seller_id_created_at_idx;
+DROP TABLE IF EXISTS seller_ratings;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
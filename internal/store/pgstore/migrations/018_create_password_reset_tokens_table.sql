@@ -0,0 +1,23 @@
+-- Write your migrate up statements here
+--
+-- Only the sha256 of the tokens is kept, the token itself is only in the email.
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash BYTEA NOT NULL UNIQUE,
+
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX password_reset_tokens_user_id_idx ON password_reset_tokens (user_id);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS password_reset_tokens_user_id_idx;
+DROP TABLE IF EXISTS password_reset_tokens;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
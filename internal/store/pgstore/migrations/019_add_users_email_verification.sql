@@ -0,0 +1,26 @@
+-- Write your migrate up statements here
+--
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+-- The accounts made before the verification existed keep bidding and selling.
+UPDATE users SET email_verified_at = created_at;
+
+-- Like the password reset tokens, only the sha256 of the tokens is kept.
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    token_hash BYTEA PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX email_verification_tokens_user_id_idx ON email_verification_tokens (user_id);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS email_verification_tokens_user_id_idx;
+DROP TABLE IF EXISTS email_verification_tokens;
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
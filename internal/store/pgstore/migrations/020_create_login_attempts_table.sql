@@ -0,0 +1,27 @@
+-- Write your migrate up statements here
+--
+-- Every login attempt, the recent failures lock the email and the ip out for
+-- a while and the rows are kept as the audit of the logins.
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email TEXT NOT NULL,
+    -- Null when the email has no account.
+    user_id UUID REFERENCES users (id) ON DELETE SET NULL,
+    ip TEXT NOT NULL,
+    -- succeeded, failed or locked.
+    outcome TEXT NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX login_attempts_email_created_at_idx ON login_attempts (email, created_at DESC);
+CREATE INDEX login_attempts_ip_created_at_idx ON login_attempts (ip, created_at DESC);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS login_attempts_ip_created_at_idx;
+DROP INDEX IF EXISTS login_attempts_email_created_at_idx;
+DROP TABLE IF EXISTS login_attempts;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
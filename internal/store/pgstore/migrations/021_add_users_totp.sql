@@ -0,0 +1,32 @@
+-- Write your migrate up statements here
+--
+-- The secret is set when the user starts the enrollment, the two factor
+-- login is only on once totp_enabled_at is set by a first valid code.
+ALTER TABLE users
+    ADD COLUMN totp_secret TEXT,
+    ADD COLUMN totp_enabled_at TIMESTAMPTZ,
+    -- The last step a code was accepted for, so each code works once.
+    ADD COLUMN totp_last_counter BIGINT NOT NULL DEFAULT 0;
+
+-- Only the sha256 of the recovery codes is kept.
+CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash BYTEA NOT NULL,
+    used_at TIMESTAMPTZ,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    UNIQUE (user_id, code_hash)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS totp_recovery_codes;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS totp_last_counter,
+    DROP COLUMN IF EXISTS totp_enabled_at,
+    DROP COLUMN IF EXISTS totp_secret;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
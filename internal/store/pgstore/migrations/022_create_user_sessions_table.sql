@@ -0,0 +1,26 @@
+-- Write your migrate up statements here
+--
+-- What the users see of their sessions, the scs sessions table only has the
+-- data. The rows are not removed with the sessions, they are hidden once the
+-- session is gone and purged a day later.
+CREATE TABLE IF NOT EXISTS user_sessions (
+    -- The token is a secret, the users refer to their sessions by id.
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    token TEXT NOT NULL UNIQUE,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    ip TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX user_sessions_user_id_idx ON user_sessions (user_id);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS user_sessions_user_id_idx;
+DROP TABLE IF EXISTS user_sessions;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
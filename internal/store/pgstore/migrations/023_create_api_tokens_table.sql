@@ -0,0 +1,26 @@
+-- Write your migrate up statements here
+--
+-- Personal tokens for the scripts of the users, only the sha256 of the token
+-- is kept.
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash BYTEA NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+
+    -- Null for the tokens that do not expire.
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX api_tokens_user_id_idx ON api_tokens (user_id);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS api_tokens_user_id_idx;
+DROP TABLE IF EXISTS api_tokens;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
@@ -0,0 +1,27 @@
+-- Write your migrate up statements here
+--
+-- The accounts of the users at the external identity providers, found by
+-- the subject the provider gives them, which never changes unlike the email.
+CREATE TABLE IF NOT EXISTS user_identities (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    -- The email the provider gave on the last login.
+    email TEXT NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_login_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS user_identities_user_id_idx;
+DROP TABLE IF EXISTS user_identities;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
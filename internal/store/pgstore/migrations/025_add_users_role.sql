@@ -0,0 +1,19 @@
+-- Write your migrate up statements here
+--
+-- The role replaces is_admin, the permissions of each role are in the code.
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'seller', 'moderator', 'admin'));
+
+-- Anyone could sell before the roles existed, the accounts made before keep selling.
+UPDATE users SET role = CASE WHEN is_admin THEN 'admin' ELSE 'seller' END;
+
+ALTER TABLE users DROP COLUMN is_admin;
+
+---- create above / drop below ----
+
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT false;
+UPDATE users SET is_admin = role = 'admin';
+ALTER TABLE users DROP COLUMN IF EXISTS role;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
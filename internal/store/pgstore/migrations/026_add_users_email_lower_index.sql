@@ -0,0 +1,14 @@
+-- Write your migrate up statements here
+--
+-- The emails are compared lowercased, so the case variants of an address are
+-- one account and one counter of failed logins.
+CREATE UNIQUE INDEX users_email_lower_idx ON users (lower(email));
+
+UPDATE login_attempts SET email = lower(btrim(email)) WHERE email <> lower(btrim(email));
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS users_email_lower_idx;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
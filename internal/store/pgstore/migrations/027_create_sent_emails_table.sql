@@ -0,0 +1,16 @@
+-- Write your migrate up statements here
+--
+-- The outbox events already emailed, an event delivered again by the outbox
+-- does not send its email twice.
+CREATE TABLE IF NOT EXISTS sent_emails (
+    event_id UUID PRIMARY KEY,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS sent_emails;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above
@@ -9,6 +9,29 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ApiToken struct {
+	ID         uuid.UUID          `json:"id"`
+	UserID     uuid.UUID          `json:"user_id"`
+	Name       string             `json:"name"`
+	TokenHash  []byte             `json:"token_hash"`
+	Scopes     []string           `json:"scopes"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type AuctionMessage struct {
+	ID         uuid.UUID          `json:"id"`
+	ProductID  uuid.UUID          `json:"product_id"`
+	SenderID   uuid.UUID          `json:"sender_id"`
+	Kind       string             `json:"kind"`
+	Body       string             `json:"body"`
+	ReplyTo    pgtype.UUID        `json:"reply_to"`
+	IsFlagged  bool               `json:"is_flagged"`
+	FlagReason pgtype.Text        `json:"flag_reason"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
 type Bid struct {
 	ID        uuid.UUID          `json:"id"`
 	ProductID uuid.UUID          `json:"product_id"`
@@ -17,17 +40,136 @@ type Bid struct {
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
+type Category struct {
+	ID              uuid.UUID          `json:"id"`
+	ParentID        pgtype.UUID        `json:"parent_id"`
+	Name            string             `json:"name"`
+	Slug            string             `json:"slug"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	AttributeSchema []byte             `json:"attribute_schema"`
+}
+
+type EmailVerificationToken struct {
+	TokenHash []byte             `json:"token_hash"`
+	UserID    uuid.UUID          `json:"user_id"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type LoginAttempt struct {
+	ID        uuid.UUID          `json:"id"`
+	Email     string             `json:"email"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	Ip        string             `json:"ip"`
+	Outcome   string             `json:"outcome"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type Notification struct {
+	ID        uuid.UUID          `json:"id"`
+	UserID    uuid.UUID          `json:"user_id"`
+	Kind      string             `json:"kind"`
+	ProductID pgtype.UUID        `json:"product_id"`
+	Title     string             `json:"title"`
+	Body      string             `json:"body"`
+	ReadAt    pgtype.Timestamptz `json:"read_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type NotificationPreference struct {
+	UserID    uuid.UUID          `json:"user_id"`
+	Kind      string             `json:"kind"`
+	Email     bool               `json:"email"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Outbox struct {
+	ID            uuid.UUID          `json:"id"`
+	EventID       uuid.UUID          `json:"event_id"`
+	Consumer      string             `json:"consumer"`
+	Payload       []byte             `json:"payload"`
+	Attempts      int32              `json:"attempts"`
+	NextAttemptAt pgtype.Timestamptz `json:"next_attempt_at"`
+	LastError     pgtype.Text        `json:"last_error"`
+	DeliveredAt   pgtype.Timestamptz `json:"delivered_at"`
+	DeadAt        pgtype.Timestamptz `json:"dead_at"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+type PasswordResetToken struct {
+	ID        uuid.UUID          `json:"id"`
+	UserID    uuid.UUID          `json:"user_id"`
+	TokenHash []byte             `json:"token_hash"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
 type Product struct {
-	ID           uuid.UUID          `json:"id"`
-	SellerID     uuid.UUID          `json:"seller_id"`
-	ProductName  string             `json:"product_name"`
-	Description  string             `json:"description"`
-	BasePrice    float64            `json:"base_price"`
-	AuctionStart pgtype.Timestamptz `json:"auction_start"`
-	AuctionEnd   pgtype.Timestamptz `json:"auction_end"`
-	IsSold       bool               `json:"is_sold"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	ID                     uuid.UUID          `json:"id"`
+	SellerID               uuid.UUID          `json:"seller_id"`
+	ProductName            string             `json:"product_name"`
+	Description            string             `json:"description"`
+	BasePrice              float64            `json:"base_price"`
+	AuctionStart           pgtype.Timestamptz `json:"auction_start"`
+	AuctionEnd             pgtype.Timestamptz `json:"auction_end"`
+	IsSold                 bool               `json:"is_sold"`
+	CreatedAt              pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt              pgtype.Timestamptz `json:"updated_at"`
+	CancelledAt            pgtype.Timestamptz `json:"cancelled_at"`
+	RelistedFrom           pgtype.UUID        `json:"relisted_from"`
+	SearchVector           interface{}        `json:"search_vector"`
+	CategoryID             pgtype.UUID        `json:"category_id"`
+	Attributes             []byte             `json:"attributes"`
+	EndingSoonNotifiedAt   pgtype.Timestamptz `json:"ending_soon_notified_at"`
+	SettledAt              pgtype.Timestamptz `json:"settled_at"`
+	SavedSearchesMatchedAt pgtype.Timestamptz `json:"saved_searches_matched_at"`
+}
+
+type ProductImage struct {
+	ID          uuid.UUID          `json:"id"`
+	ProductID   uuid.UUID          `json:"product_id"`
+	Position    int32              `json:"position"`
+	StorageKey  string             `json:"storage_key"`
+	ContentType string             `json:"content_type"`
+	Width       int32              `json:"width"`
+	Height      int32              `json:"height"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type SavedSearch struct {
+	ID              uuid.UUID          `json:"id"`
+	UserID          uuid.UUID          `json:"user_id"`
+	Name            string             `json:"name"`
+	Query           string             `json:"query"`
+	CategoryID      pgtype.UUID        `json:"category_id"`
+	MinPrice        pgtype.Float8      `json:"min_price"`
+	MaxPrice        pgtype.Float8      `json:"max_price"`
+	Attributes      []byte             `json:"attributes"`
+	AttributeRanges []byte             `json:"attribute_ranges"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+}
+
+type SavedSearchMatch struct {
+	UserID        uuid.UUID          `json:"user_id"`
+	ProductID     uuid.UUID          `json:"product_id"`
+	SavedSearchID pgtype.UUID        `json:"saved_search_id"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+type SellerRating struct {
+	ProductID uuid.UUID          `json:"product_id"`
+	SellerID  uuid.UUID          `json:"seller_id"`
+	BuyerID   uuid.UUID          `json:"buyer_id"`
+	Score     int32              `json:"score"`
+	Comment   string             `json:"comment"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type SentEmail struct {
+	EventID   uuid.UUID          `json:"event_id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
 type Session struct {
@@ -36,12 +178,72 @@ type Session struct {
 	Expiry pgtype.Timestamptz `json:"expiry"`
 }
 
+type TotpRecoveryCode struct {
+	ID        uuid.UUID          `json:"id"`
+	UserID    uuid.UUID          `json:"user_id"`
+	CodeHash  []byte             `json:"code_hash"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
 type User struct {
-	ID           uuid.UUID          `json:"id"`
-	UserName     string             `json:"user_name"`
-	Email        string             `json:"email"`
-	PasswordHash []byte             `json:"password_hash"`
-	Bio          string             `json:"bio"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	ID              uuid.UUID          `json:"id"`
+	UserName        string             `json:"user_name"`
+	Email           string             `json:"email"`
+	PasswordHash    []byte             `json:"password_hash"`
+	Bio             string             `json:"bio"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	EmailVerifiedAt pgtype.Timestamptz `json:"email_verified_at"`
+	TotpSecret      pgtype.Text        `json:"totp_secret"`
+	TotpEnabledAt   pgtype.Timestamptz `json:"totp_enabled_at"`
+	TotpLastCounter int64              `json:"totp_last_counter"`
+	Role            string             `json:"role"`
+}
+
+type UserIdentity struct {
+	ID          uuid.UUID          `json:"id"`
+	UserID      uuid.UUID          `json:"user_id"`
+	Provider    string             `json:"provider"`
+	Subject     string             `json:"subject"`
+	Email       string             `json:"email"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	LastLoginAt pgtype.Timestamptz `json:"last_login_at"`
+}
+
+type UserSession struct {
+	ID         uuid.UUID          `json:"id"`
+	Token      string             `json:"token"`
+	UserID     uuid.UUID          `json:"user_id"`
+	Ip         string             `json:"ip"`
+	UserAgent  string             `json:"user_agent"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	LastSeenAt pgtype.Timestamptz `json:"last_seen_at"`
+}
+
+type Watchlist struct {
+	UserID    uuid.UUID          `json:"user_id"`
+	ProductID uuid.UUID          `json:"product_id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID         uuid.UUID          `json:"id"`
+	EndpointID uuid.UUID          `json:"endpoint_id"`
+	DeliveryID uuid.UUID          `json:"delivery_id"`
+	EventType  string             `json:"event_type"`
+	Payload    []byte             `json:"payload"`
+	StatusCode pgtype.Int4        `json:"status_code"`
+	Error      pgtype.Text        `json:"error"`
+	DurationMs int32              `json:"duration_ms"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type WebhookEndpoint struct {
+	ID        uuid.UUID          `json:"id"`
+	UserID    uuid.UUID          `json:"user_id"`
+	Url       string             `json:"url"`
+	Secret    string             `json:"secret"`
+	Events    []string           `json:"events"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
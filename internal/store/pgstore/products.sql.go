@@ -12,11 +12,62 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const cancelProduct = `-- name: CancelProduct :exec
+UPDATE products
+SET cancelled_at = now(), updated_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) CancelProduct(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, cancelProduct, id)
+	return err
+}
+
+const claimEndingSoonAuctions = `-- name: ClaimEndingSoonAuctions :many
+UPDATE products
+SET ending_soon_notified_at = now()
+WHERE auction_start <= now()
+    AND auction_end > now()
+    AND auction_end <= $1::timestamptz
+    AND ending_soon_notified_at IS NULL
+    AND cancelled_at IS NULL
+    AND is_sold = false
+RETURNING id, product_name, auction_end
+`
+
+type ClaimEndingSoonAuctionsRow struct {
+	ID          uuid.UUID          `json:"id"`
+	ProductName string             `json:"product_name"`
+	AuctionEnd  pgtype.Timestamptz `json:"auction_end"`
+}
+
+// Marks the live auctions ending before ends_before as notified, each one is
+// returned only once.
+func (q *Queries) ClaimEndingSoonAuctions(ctx context.Context, endsBefore pgtype.Timestamptz) ([]ClaimEndingSoonAuctionsRow, error) {
+	rows, err := q.db.Query(ctx, claimEndingSoonAuctions, endsBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimEndingSoonAuctionsRow
+	for rows.Next() {
+		var i ClaimEndingSoonAuctionsRow
+		if err := rows.Scan(&i.ID, &i.ProductName, &i.AuctionEnd); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createProduct = `-- name: CreateProduct :one
 INSERT INTO products (
     seller_id, product_name, description,
-    base_price, auction_start, auction_end
-) VALUES ($1, $2, $3, $4, $5, $6)
+    base_price, auction_start, auction_end, category_id, attributes
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 RETURNING id
 `
 
@@ -27,6 +78,8 @@ type CreateProductParams struct {
 	BasePrice    float64            `json:"base_price"`
 	AuctionStart pgtype.Timestamptz `json:"auction_start"`
 	AuctionEnd   pgtype.Timestamptz `json:"auction_end"`
+	CategoryID   pgtype.UUID        `json:"category_id"`
+	Attributes   []byte             `json:"attributes"`
 }
 
 func (q *Queries) CreateProduct(ctx context.Context, arg CreateProductParams) (uuid.UUID, error) {
@@ -37,6 +90,8 @@ func (q *Queries) CreateProduct(ctx context.Context, arg CreateProductParams) (u
 		arg.BasePrice,
 		arg.AuctionStart,
 		arg.AuctionEnd,
+		arg.CategoryID,
+		arg.Attributes,
 	)
 	var id uuid.UUID
 	err := row.Scan(&id)
@@ -62,7 +117,10 @@ SELECT
     base_price,
     auction_start,
     auction_end,
-    is_sold
+    is_sold,
+    cancelled_at,
+    category_id,
+    attributes
 FROM products
 WHERE id = $1
 `
@@ -76,6 +134,9 @@ type GetProductByIdRow struct {
 	AuctionStart pgtype.Timestamptz `json:"auction_start"`
 	AuctionEnd   pgtype.Timestamptz `json:"auction_end"`
 	IsSold       bool               `json:"is_sold"`
+	CancelledAt  pgtype.Timestamptz `json:"cancelled_at"`
+	CategoryID   pgtype.UUID        `json:"category_id"`
+	Attributes   []byte             `json:"attributes"`
 }
 
 func (q *Queries) GetProductById(ctx context.Context, id uuid.UUID) (GetProductByIdRow, error) {
@@ -90,6 +151,62 @@ func (q *Queries) GetProductById(ctx context.Context, id uuid.UUID) (GetProductB
 		&i.AuctionStart,
 		&i.AuctionEnd,
 		&i.IsSold,
+		&i.CancelledAt,
+		&i.CategoryID,
+		&i.Attributes,
+	)
+	return i, err
+}
+
+const getProductByIdForUpdate = `-- name: GetProductByIdForUpdate :one
+SELECT
+    id,
+    seller_id,
+    product_name,
+    description,
+    base_price,
+    auction_start,
+    auction_end,
+    is_sold,
+    cancelled_at,
+    category_id,
+    attributes
+FROM products
+WHERE id = $1
+FOR UPDATE
+`
+
+type GetProductByIdForUpdateRow struct {
+	ID           uuid.UUID          `json:"id"`
+	SellerID     uuid.UUID          `json:"seller_id"`
+	ProductName  string             `json:"product_name"`
+	Description  string             `json:"description"`
+	BasePrice    float64            `json:"base_price"`
+	AuctionStart pgtype.Timestamptz `json:"auction_start"`
+	AuctionEnd   pgtype.Timestamptz `json:"auction_end"`
+	IsSold       bool               `json:"is_sold"`
+	CancelledAt  pgtype.Timestamptz `json:"cancelled_at"`
+	CategoryID   pgtype.UUID        `json:"category_id"`
+	Attributes   []byte             `json:"attributes"`
+}
+
+// Keep the columns of GetProductById, the services convert its rows to the
+// GetProductById ones.
+func (q *Queries) GetProductByIdForUpdate(ctx context.Context, id uuid.UUID) (GetProductByIdForUpdateRow, error) {
+	row := q.db.QueryRow(ctx, getProductByIdForUpdate, id)
+	var i GetProductByIdForUpdateRow
+	err := row.Scan(
+		&i.ID,
+		&i.SellerID,
+		&i.ProductName,
+		&i.Description,
+		&i.BasePrice,
+		&i.AuctionStart,
+		&i.AuctionEnd,
+		&i.IsSold,
+		&i.CancelledAt,
+		&i.CategoryID,
+		&i.Attributes,
 	)
 	return i, err
 }
@@ -167,7 +284,7 @@ SELECT
     auction_end,
     is_sold
 FROM products
-WHERE auction_end > now() AND is_sold = false
+WHERE auction_end > now() AND is_sold = false AND cancelled_at IS NULL
 `
 
 type ListActiveAndUpcomingAuctionsRow struct {
@@ -281,7 +398,7 @@ SELECT
     auction_end,
     is_sold
 FROM products
-WHERE auction_end > now() AND auction_start < now() AND is_sold = false
+WHERE auction_end > now() AND auction_start < now() AND is_sold = false AND cancelled_at IS NULL
 `
 
 type ListLiveProductAuctionsRow struct {
@@ -323,3 +440,419 @@ func (q *Queries) ListLiveProductAuctions(ctx context.Context) ([]ListLiveProduc
 	}
 	return items, nil
 }
+
+const listProducts = `-- name: ListProducts :many
+SELECT
+    p.id,
+    p.seller_id,
+    p.product_name,
+    p.description,
+    p.base_price,
+    p.auction_start,
+    p.auction_end,
+    p.is_sold,
+    p.cancelled_at,
+    p.category_id,
+    p.attributes,
+    p.created_at,
+    COALESCE(b.highest_bid, 0)::float AS highest_bid,
+    COALESCE(b.bid_count, 0)::bigint AS bid_count
+FROM products p
+LEFT JOIN LATERAL (
+    SELECT MAX(bid_amount) AS highest_bid, COUNT(*) AS bid_count
+    FROM bids
+    WHERE bids.product_id = p.id
+) b ON true
+WHERE
+    ($1::text IS NULL OR CASE $1::text
+        WHEN 'upcoming' THEN p.auction_start > now() AND p.is_sold = false AND p.cancelled_at IS NULL
+        WHEN 'live' THEN p.auction_start <= now() AND p.auction_end > now() AND p.is_sold = false AND p.cancelled_at IS NULL
+        WHEN 'ended' THEN p.auction_end <= now() AND p.is_sold = false AND p.cancelled_at IS NULL
+        WHEN 'sold' THEN p.is_sold = true
+        WHEN 'cancelled' THEN p.cancelled_at IS NOT NULL
+    END)
+    AND ($2::uuid IS NULL OR p.seller_id = $2::uuid)
+    AND ($3::float IS NULL OR COALESCE(b.highest_bid, p.base_price) >= $3::float)
+    AND ($4::float IS NULL OR COALESCE(b.highest_bid, p.base_price) <= $4::float)
+    AND ($5::uuid IS NULL OR p.category_id IN (
+        WITH RECURSIVE tree AS (
+            SELECT id FROM categories WHERE categories.id = $5::uuid
+            UNION ALL
+            SELECT c.id FROM categories c
+            JOIN tree t ON c.parent_id = t.id
+        )
+        SELECT id FROM tree
+    ))
+    AND ($6::jsonb IS NULL OR p.attributes @> $6::jsonb)
+    AND ($7::jsonb IS NULL OR NOT EXISTS (
+        SELECT 1 FROM jsonb_each($7::jsonb) r
+        WHERE CASE WHEN jsonb_typeof(p.attributes -> r.key) = 'number' THEN
+            (r.value -> 'min' IS NOT NULL AND (p.attributes ->> r.key)::numeric < (r.value ->> 'min')::numeric)
+            OR (r.value -> 'max' IS NOT NULL AND (p.attributes ->> r.key)::numeric > (r.value ->> 'max')::numeric)
+        ELSE true END
+    ))
+    AND (
+        $8::timestamptz IS NULL
+        OR ($9::text = 'ending_soon' AND (p.auction_end, p.id) > ($8::timestamptz, $10::uuid))
+        OR ($9::text <> 'ending_soon' AND (p.created_at, p.id) < ($8::timestamptz, $10::uuid))
+    )
+    AND ($9::text <> 'ending_soon' OR (p.auction_end > now() AND p.is_sold = false AND p.cancelled_at IS NULL))
+ORDER BY
+    CASE WHEN $9::text = 'ending_soon' THEN p.auction_end END ASC,
+    CASE WHEN $9::text = 'ending_soon' THEN p.id END ASC,
+    p.created_at DESC,
+    p.id DESC
+LIMIT $11
+`
+
+type ListProductsParams struct {
+	Status          pgtype.Text        `json:"status"`
+	SellerID        pgtype.UUID        `json:"seller_id"`
+	MinPrice        pgtype.Float8      `json:"min_price"`
+	MaxPrice        pgtype.Float8      `json:"max_price"`
+	CategoryID      pgtype.UUID        `json:"category_id"`
+	Attributes      []byte             `json:"attributes"`
+	AttributeRanges []byte             `json:"attribute_ranges"`
+	CursorTime      pgtype.Timestamptz `json:"cursor_time"`
+	Sort            string             `json:"sort"`
+	CursorID        pgtype.UUID        `json:"cursor_id"`
+	PageSize        int32              `json:"page_size"`
+}
+
+type ListProductsRow struct {
+	ID           uuid.UUID          `json:"id"`
+	SellerID     uuid.UUID          `json:"seller_id"`
+	ProductName  string             `json:"product_name"`
+	Description  string             `json:"description"`
+	BasePrice    float64            `json:"base_price"`
+	AuctionStart pgtype.Timestamptz `json:"auction_start"`
+	AuctionEnd   pgtype.Timestamptz `json:"auction_end"`
+	IsSold       bool               `json:"is_sold"`
+	CancelledAt  pgtype.Timestamptz `json:"cancelled_at"`
+	CategoryID   pgtype.UUID        `json:"category_id"`
+	Attributes   []byte             `json:"attributes"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	HighestBid   float64            `json:"highest_bid"`
+	BidCount     int64              `json:"bid_count"`
+}
+
+func (q *Queries) ListProducts(ctx context.Context, arg ListProductsParams) ([]ListProductsRow, error) {
+	rows, err := q.db.Query(ctx, listProducts,
+		arg.Status,
+		arg.SellerID,
+		arg.MinPrice,
+		arg.MaxPrice,
+		arg.CategoryID,
+		arg.Attributes,
+		arg.AttributeRanges,
+		arg.CursorTime,
+		arg.Sort,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListProductsRow
+	for rows.Next() {
+		var i ListProductsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.SellerID,
+			&i.ProductName,
+			&i.Description,
+			&i.BasePrice,
+			&i.AuctionStart,
+			&i.AuctionEnd,
+			&i.IsSold,
+			&i.CancelledAt,
+			&i.CategoryID,
+			&i.Attributes,
+			&i.CreatedAt,
+			&i.HighestBid,
+			&i.BidCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnsettledAuctions = `-- name: ListUnsettledAuctions :many
+SELECT id FROM products
+WHERE auction_end <= now() AND settled_at IS NULL AND cancelled_at IS NULL
+ORDER BY auction_end
+LIMIT $1
+`
+
+func (q *Queries) ListUnsettledAuctions(ctx context.Context, limit int32) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, listUnsettledAuctions, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const relistProduct = `-- name: RelistProduct :one
+INSERT INTO products (
+    seller_id, product_name, description,
+    base_price, auction_start, auction_end, relisted_from, category_id, attributes
+)
+SELECT seller_id, product_name, description, $2, $3, $4, id, category_id, attributes
+FROM products
+WHERE id = $1
+RETURNING id
+`
+
+type RelistProductParams struct {
+	ID           uuid.UUID          `json:"id"`
+	BasePrice    float64            `json:"base_price"`
+	AuctionStart pgtype.Timestamptz `json:"auction_start"`
+	AuctionEnd   pgtype.Timestamptz `json:"auction_end"`
+}
+
+func (q *Queries) RelistProduct(ctx context.Context, arg RelistProductParams) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, relistProduct,
+		arg.ID,
+		arg.BasePrice,
+		arg.AuctionStart,
+		arg.AuctionEnd,
+	)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const searchProducts = `-- name: SearchProducts :many
+WITH q AS (
+    SELECT websearch_to_tsquery('english', $1::text) AS query
+), page AS (
+    SELECT
+        p.id,
+        p.seller_id,
+        p.product_name,
+        p.description,
+        p.base_price,
+        p.auction_start,
+        p.auction_end,
+        p.is_sold,
+        p.cancelled_at,
+        p.category_id,
+        p.attributes,
+        ts_rank(p.search_vector, q.query)::float AS rank,
+        COALESCE(b.highest_bid, 0)::float AS highest_bid,
+        COALESCE(b.bid_count, 0)::bigint AS bid_count
+    FROM products p
+    CROSS JOIN q
+    LEFT JOIN LATERAL (
+        SELECT MAX(bid_amount) AS highest_bid, COUNT(*) AS bid_count
+        FROM bids
+        WHERE bids.product_id = p.id
+    ) b ON true
+    WHERE
+        p.search_vector @@ q.query
+        AND ($2::text IS NULL OR CASE $2::text
+            WHEN 'upcoming' THEN p.auction_start > now() AND p.is_sold = false AND p.cancelled_at IS NULL
+            WHEN 'live' THEN p.auction_start <= now() AND p.auction_end > now() AND p.is_sold = false AND p.cancelled_at IS NULL
+            WHEN 'ended' THEN p.auction_end <= now() AND p.is_sold = false AND p.cancelled_at IS NULL
+            WHEN 'sold' THEN p.is_sold = true
+            WHEN 'cancelled' THEN p.cancelled_at IS NOT NULL
+        END)
+        AND ($3::uuid IS NULL OR p.seller_id = $3::uuid)
+        AND ($4::float IS NULL OR COALESCE(b.highest_bid, p.base_price) >= $4::float)
+        AND ($5::float IS NULL OR COALESCE(b.highest_bid, p.base_price) <= $5::float)
+        AND ($6::uuid IS NULL OR p.category_id IN (
+            WITH RECURSIVE tree AS (
+                SELECT id FROM categories WHERE categories.id = $6::uuid
+                UNION ALL
+                SELECT c.id FROM categories c
+                JOIN tree t ON c.parent_id = t.id
+            )
+            SELECT id FROM tree
+        ))
+        AND ($7::jsonb IS NULL OR p.attributes @> $7::jsonb)
+        AND ($8::jsonb IS NULL OR NOT EXISTS (
+            SELECT 1 FROM jsonb_each($8::jsonb) r
+            WHERE CASE WHEN jsonb_typeof(p.attributes -> r.key) = 'number' THEN
+                (r.value -> 'min' IS NOT NULL AND (p.attributes ->> r.key)::numeric < (r.value ->> 'min')::numeric)
+                OR (r.value -> 'max' IS NOT NULL AND (p.attributes ->> r.key)::numeric > (r.value ->> 'max')::numeric)
+            ELSE true END
+        ))
+        AND (
+            $9::float IS NULL
+            OR (ts_rank(p.search_vector, q.query)::float, p.id) < ($9::float, $10::uuid)
+        )
+    ORDER BY rank DESC, p.id DESC
+    LIMIT $11
+)
+SELECT
+    page.id,
+    page.seller_id,
+    page.product_name,
+    page.description,
+    page.base_price,
+    page.auction_start,
+    page.auction_end,
+    page.is_sold,
+    page.cancelled_at,
+    page.category_id,
+    page.attributes,
+    page.rank,
+    page.highest_bid,
+    page.bid_count,
+    ts_headline('english', page.product_name, q.query, 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>')::text AS name_highlight,
+    ts_headline('english', page.description, q.query, 'MaxFragments=2, MaxWords=20, MinWords=5, StartSel=<mark>, StopSel=</mark>')::text AS snippet
+FROM page
+CROSS JOIN q
+ORDER BY page.rank DESC, page.id DESC
+`
+
+type SearchProductsParams struct {
+	Query           string        `json:"query"`
+	Status          pgtype.Text   `json:"status"`
+	SellerID        pgtype.UUID   `json:"seller_id"`
+	MinPrice        pgtype.Float8 `json:"min_price"`
+	MaxPrice        pgtype.Float8 `json:"max_price"`
+	CategoryID      pgtype.UUID   `json:"category_id"`
+	Attributes      []byte        `json:"attributes"`
+	AttributeRanges []byte        `json:"attribute_ranges"`
+	CursorRank      pgtype.Float8 `json:"cursor_rank"`
+	CursorID        pgtype.UUID   `json:"cursor_id"`
+	PageSize        int32         `json:"page_size"`
+}
+
+type SearchProductsRow struct {
+	ID            uuid.UUID          `json:"id"`
+	SellerID      uuid.UUID          `json:"seller_id"`
+	ProductName   string             `json:"product_name"`
+	Description   string             `json:"description"`
+	BasePrice     float64            `json:"base_price"`
+	AuctionStart  pgtype.Timestamptz `json:"auction_start"`
+	AuctionEnd    pgtype.Timestamptz `json:"auction_end"`
+	IsSold        bool               `json:"is_sold"`
+	CancelledAt   pgtype.Timestamptz `json:"cancelled_at"`
+	CategoryID    pgtype.UUID        `json:"category_id"`
+	Attributes    []byte             `json:"attributes"`
+	Rank          float64            `json:"rank"`
+	HighestBid    float64            `json:"highest_bid"`
+	BidCount      int64              `json:"bid_count"`
+	NameHighlight string             `json:"name_highlight"`
+	Snippet       string             `json:"snippet"`
+}
+
+func (q *Queries) SearchProducts(ctx context.Context, arg SearchProductsParams) ([]SearchProductsRow, error) {
+	rows, err := q.db.Query(ctx, searchProducts,
+		arg.Query,
+		arg.Status,
+		arg.SellerID,
+		arg.MinPrice,
+		arg.MaxPrice,
+		arg.CategoryID,
+		arg.Attributes,
+		arg.AttributeRanges,
+		arg.CursorRank,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchProductsRow
+	for rows.Next() {
+		var i SearchProductsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.SellerID,
+			&i.ProductName,
+			&i.Description,
+			&i.BasePrice,
+			&i.AuctionStart,
+			&i.AuctionEnd,
+			&i.IsSold,
+			&i.CancelledAt,
+			&i.CategoryID,
+			&i.Attributes,
+			&i.Rank,
+			&i.HighestBid,
+			&i.BidCount,
+			&i.NameHighlight,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const settleProduct = `-- name: SettleProduct :execrows
+UPDATE products
+SET is_sold = $2, settled_at = now(), updated_at = now()
+WHERE id = $1 AND settled_at IS NULL
+`
+
+type SettleProductParams struct {
+	ID     uuid.UUID `json:"id"`
+	IsSold bool      `json:"is_sold"`
+}
+
+func (q *Queries) SettleProduct(ctx context.Context, arg SettleProductParams) (int64, error) {
+	result, err := q.db.Exec(ctx, settleProduct, arg.ID, arg.IsSold)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateProduct = `-- name: UpdateProduct :exec
+UPDATE products
+SET product_name = $2, description = $3, base_price = $4,
+    auction_start = $5, auction_end = $6, attributes = $7,
+    ending_soon_notified_at = CASE WHEN auction_end = $6 THEN ending_soon_notified_at END,
+    updated_at = now()
+WHERE id = $1
+`
+
+type UpdateProductParams struct {
+	ID           uuid.UUID          `json:"id"`
+	ProductName  string             `json:"product_name"`
+	Description  string             `json:"description"`
+	BasePrice    float64            `json:"base_price"`
+	AuctionStart pgtype.Timestamptz `json:"auction_start"`
+	AuctionEnd   pgtype.Timestamptz `json:"auction_end"`
+	Attributes   []byte             `json:"attributes"`
+}
+
+func (q *Queries) UpdateProduct(ctx context.Context, arg UpdateProductParams) error {
+	_, err := q.db.Exec(ctx, updateProduct,
+		arg.ID,
+		arg.ProductName,
+		arg.Description,
+		arg.BasePrice,
+		arg.AuctionStart,
+		arg.AuctionEnd,
+		arg.Attributes,
+	)
+	return err
+}
@@ -0,0 +1,27 @@
+-- name: CreateApiToken :one
+INSERT INTO api_tokens ("user_id", "name", "token_hash", "scopes", "expires_at")
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at;
+
+-- name: CountApiTokensByUserId :one
+SELECT COUNT(*) FROM api_tokens
+WHERE user_id = $1;
+
+-- name: ListApiTokensByUserId :many
+SELECT id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at
+FROM api_tokens
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: UseApiToken :one
+-- Finds the token that did not expire and records its use.
+UPDATE api_tokens
+SET last_used_at = NOW()
+WHERE token_hash = $1
+    AND (expires_at IS NULL OR expires_at > NOW())
+RETURNING id, user_id, scopes;
+
+-- name: DeleteApiToken :execrows
+DELETE FROM api_tokens
+WHERE id = $1
+    AND user_id = $2;
@@ -0,0 +1,15 @@
+-- name: CreateAuctionMessage :one
+INSERT INTO auction_messages (
+    product_id, sender_id, kind, body, reply_to, is_flagged, flag_reason
+) VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetAuctionMessageById :one
+SELECT * FROM auction_messages
+WHERE id = $1;
+
+-- name: ListRecentAuctionMessages :many
+SELECT * FROM auction_messages
+WHERE product_id = $1 AND is_flagged = false
+ORDER BY created_at DESC
+LIMIT $2;
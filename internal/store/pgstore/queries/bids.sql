@@ -14,3 +14,10 @@ SELECT * FROM bids
 WHERE product_id = $1
 ORDER BY bid_amount DESC
 LIMIT 1;
+
+-- name: GetBidStatsByProductId :one
+SELECT
+    COALESCE(MAX(bid_amount), 0)::float AS highest_bid,
+    COUNT(*) AS bid_count
+FROM bids
+WHERE product_id = $1;
@@ -0,0 +1,47 @@
+-- name: CreateCategory :one
+INSERT INTO categories (parent_id, name, slug, attribute_schema)
+VALUES ($1, $2, $3, $4)
+RETURNING id, parent_id, name, slug, created_at, updated_at, attribute_schema;
+
+-- name: UpdateCategory :one
+UPDATE categories
+SET parent_id = $2, name = $3, slug = $4, attribute_schema = $5, updated_at = now()
+WHERE id = $1
+RETURNING id, parent_id, name, slug, created_at, updated_at, attribute_schema;
+
+-- name: DeleteCategory :exec
+DELETE FROM categories
+WHERE id = $1;
+
+-- name: GetCategoryById :one
+SELECT id, parent_id, name, slug, created_at, updated_at, attribute_schema
+FROM categories
+WHERE id = $1;
+
+-- name: ListCategories :many
+SELECT id, parent_id, name, slug, created_at, updated_at, attribute_schema
+FROM categories
+ORDER BY name;
+
+-- name: IsCategoryDescendant :one
+WITH RECURSIVE tree AS (
+    SELECT id FROM categories WHERE categories.id = @ancestor_id::uuid
+    UNION ALL
+    SELECT c.id FROM categories c
+    JOIN tree t ON c.parent_id = t.id
+)
+SELECT EXISTS (SELECT 1 FROM tree WHERE tree.id = @category_id::uuid)::boolean;
+
+-- name: GetCategorySchemas :many
+-- Returns the schemas of the category and its ancestors, root first.
+WITH RECURSIVE ancestors AS (
+    SELECT id, parent_id, attribute_schema, 0 AS depth
+    FROM categories
+    WHERE categories.id = $1
+    UNION ALL
+    SELECT c.id, c.parent_id, c.attribute_schema, a.depth + 1
+    FROM categories c
+    JOIN ancestors a ON c.id = a.parent_id
+)
+SELECT attribute_schema FROM ancestors
+ORDER BY depth DESC;
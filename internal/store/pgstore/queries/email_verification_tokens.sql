@@ -0,0 +1,14 @@
+-- name: CreateEmailVerificationToken :exec
+INSERT INTO email_verification_tokens ("token_hash", "user_id", "expires_at")
+VALUES ($1, $2, $3);
+
+-- name: UseEmailVerificationToken :one
+-- A token works once and only before it expires.
+DELETE FROM email_verification_tokens
+WHERE token_hash = $1
+    AND expires_at > NOW()
+RETURNING user_id;
+
+-- name: DeleteEmailVerificationTokensByUserId :exec
+DELETE FROM email_verification_tokens
+WHERE user_id = $1;
@@ -0,0 +1,32 @@
+-- name: CreateLoginAttempt :exec
+INSERT INTO login_attempts ("email", "user_id", "ip", "outcome")
+VALUES ($1, $2, $3, $4);
+
+-- name: GetLoginFailuresByEmail :one
+-- The failures since @since and since the last successful login.
+SELECT COUNT(*) AS failures, MAX(created_at)::timestamptz AS last_failure
+FROM login_attempts
+WHERE email = @email
+    AND outcome = 'failed'
+    AND created_at > @since
+    AND created_at > COALESCE(
+        (SELECT MAX(created_at) FROM login_attempts WHERE email = @email AND outcome = 'succeeded'),
+        '-infinity'
+    );
+
+-- name: GetLoginFailuresByIp :one
+SELECT COUNT(*) AS failures, MAX(created_at)::timestamptz AS last_failure
+FROM login_attempts
+WHERE ip = @ip
+    AND outcome = 'failed'
+    AND created_at > @since;
+
+-- name: ListLoginAttempts :many
+-- The newest first, filtered by the given email, ip and outcome.
+SELECT id, email, user_id, ip, outcome, created_at
+FROM login_attempts
+WHERE (sqlc.narg('email')::text IS NULL OR email = sqlc.narg('email'))
+    AND (sqlc.narg('ip')::text IS NULL OR ip = sqlc.narg('ip'))
+    AND (sqlc.narg('outcome')::text IS NULL OR outcome = sqlc.narg('outcome'))
+ORDER BY created_at DESC
+LIMIT @page_size;
@@ -0,0 +1,16 @@
+-- name: ListNotificationPreferencesByUserId :many
+SELECT kind, email FROM notification_preferences
+WHERE user_id = $1;
+
+-- name: SetNotificationPreference :exec
+INSERT INTO notification_preferences (user_id, kind, email)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, kind) DO UPDATE
+SET email = EXCLUDED.email, updated_at = now();
+
+-- name: GetEmailRecipient :one
+-- The address of the user and whether they want the email of the kind.
+SELECT u.email, u.user_name, COALESCE(p.email, true)::boolean AS send_email
+FROM users u
+LEFT JOIN notification_preferences p ON p.user_id = u.id AND p.kind = @kind
+WHERE u.id = @user_id;
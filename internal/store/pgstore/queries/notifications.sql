@@ -0,0 +1,34 @@
+-- name: CreateNotification :one
+-- Returns no rows when the notification with the id already exists.
+INSERT INTO notifications (
+    id, user_id, kind, product_id, title, body
+) VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (id) DO NOTHING
+RETURNING id, user_id, kind, product_id, title, body, read_at, created_at;
+
+-- name: ListNotificationsByUserId :many
+SELECT id, user_id, kind, product_id, title, body, read_at, created_at
+FROM notifications
+WHERE user_id = @user_id
+    AND (NOT @unread_only::boolean OR read_at IS NULL)
+    -- Keyset pagination, newest first.
+    AND (
+        sqlc.narg('cursor_time')::timestamptz IS NULL
+        OR (created_at, id) < (sqlc.narg('cursor_time')::timestamptz, sqlc.narg('cursor_id')::uuid)
+    )
+ORDER BY created_at DESC, id DESC
+LIMIT @page_size;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications
+SET read_at = COALESCE(read_at, now())
+WHERE id = $1 AND user_id = $2;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read_at = now()
+WHERE user_id = $1 AND read_at IS NULL;
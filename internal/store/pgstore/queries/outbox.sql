@@ -0,0 +1,45 @@
+-- name: EnqueueOutboxEvent :exec
+INSERT INTO outbox (event_id, consumer, payload)
+VALUES ($1, $2, $3);
+
+-- name: ClaimOutboxEvents :many
+-- Leases the due events until lease_until, an event whose delivery crashed
+-- is claimed again once its lease is over.
+UPDATE outbox
+SET attempts = attempts + 1, next_attempt_at = @lease_until::timestamptz
+WHERE id IN (
+    SELECT id FROM outbox
+    WHERE delivered_at IS NULL AND dead_at IS NULL AND next_attempt_at <= now()
+    ORDER BY next_attempt_at
+    LIMIT @batch_size
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, event_id, consumer, payload, attempts;
+
+-- name: MarkOutboxEventDelivered :exec
+UPDATE outbox
+SET delivered_at = now(), last_error = NULL
+WHERE id = $1;
+
+-- name: RetryOutboxEvent :exec
+UPDATE outbox
+SET next_attempt_at = $2, last_error = $3
+WHERE id = $1;
+
+-- name: DeadLetterOutboxEvent :exec
+UPDATE outbox
+SET dead_at = now(), last_error = $2
+WHERE id = $1;
+
+-- name: ListDeadOutboxEvents :many
+SELECT id, event_id, consumer, payload, attempts, next_attempt_at, last_error,
+    delivered_at, dead_at, created_at
+FROM outbox
+WHERE dead_at IS NOT NULL
+ORDER BY dead_at DESC
+LIMIT $1;
+
+-- name: RequeueDeadOutboxEvent :execrows
+UPDATE outbox
+SET dead_at = NULL, attempts = 0, next_attempt_at = now()
+WHERE id = $1 AND dead_at IS NOT NULL;
@@ -0,0 +1,16 @@
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens ("user_id", "token_hash", "expires_at")
+VALUES ($1, $2, $3);
+
+-- name: UsePasswordResetToken :one
+-- A token works once and only before it expires.
+UPDATE password_reset_tokens
+SET used_at = NOW()
+WHERE token_hash = $1
+    AND used_at IS NULL
+    AND expires_at > NOW()
+RETURNING user_id;
+
+-- name: DeletePasswordResetTokensByUserId :exec
+DELETE FROM password_reset_tokens
+WHERE user_id = $1;
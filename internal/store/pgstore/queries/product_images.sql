@@ -0,0 +1,26 @@
+-- name: CreateProductImage :one
+INSERT INTO product_images (
+    id, product_id, position, storage_key, content_type, width, height
+) VALUES (
+    $1, $2,
+    (SELECT COALESCE(MAX(position), 0) + 1 FROM product_images WHERE product_id = $2),
+    $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: CountProductImages :one
+SELECT COUNT(*) FROM product_images
+WHERE product_id = $1;
+
+-- name: GetProductImageById :one
+SELECT * FROM product_images
+WHERE id = $1;
+
+-- name: DeleteProductImage :exec
+DELETE FROM product_images
+WHERE id = $1;
+
+-- name: ListProductImagesByProductIds :many
+SELECT * FROM product_images
+WHERE product_id = ANY(@product_ids::uuid[])
+ORDER BY product_id, position;
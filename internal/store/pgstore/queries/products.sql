@@ -1,8 +1,8 @@
 -- name: CreateProduct :one
 INSERT INTO products (
     seller_id, product_name, description,
-    base_price, auction_start, auction_end
-) VALUES ($1, $2, $3, $4, $5, $6)
+    base_price, auction_start, auction_end, category_id, attributes
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 RETURNING id;
 
 -- name: DeleteProduct :exec
@@ -18,10 +18,55 @@ SELECT
     base_price,
     auction_start,
     auction_end,
-    is_sold
+    is_sold,
+    cancelled_at,
+    category_id,
+    attributes
+FROM products
+WHERE id = $1;
+
+-- name: GetProductByIdForUpdate :one
+-- Keep the columns of GetProductById, the services convert its rows to the
+-- GetProductById ones.
+SELECT
+    id,
+    seller_id,
+    product_name,
+    description,
+    base_price,
+    auction_start,
+    auction_end,
+    is_sold,
+    cancelled_at,
+    category_id,
+    attributes
 FROM products
+WHERE id = $1
+FOR UPDATE;
+
+-- name: UpdateProduct :exec
+UPDATE products
+SET product_name = $2, description = $3, base_price = $4,
+    auction_start = $5, auction_end = $6, attributes = $7,
+    ending_soon_notified_at = CASE WHEN auction_end = $6 THEN ending_soon_notified_at END,
+    updated_at = now()
 WHERE id = $1;
 
+-- name: CancelProduct :exec
+UPDATE products
+SET cancelled_at = now(), updated_at = now()
+WHERE id = $1;
+
+-- name: RelistProduct :one
+INSERT INTO products (
+    seller_id, product_name, description,
+    base_price, auction_start, auction_end, relisted_from, category_id, attributes
+)
+SELECT seller_id, product_name, description, $2, $3, $4, id, category_id, attributes
+FROM products
+WHERE id = $1
+RETURNING id;
+
 -- name: ListLiveProductAuctions :many
 SELECT
     id,
@@ -33,7 +78,7 @@ SELECT
     auction_end,
     is_sold
 FROM products
-WHERE auction_end > now() AND auction_start < now() AND is_sold = false;
+WHERE auction_end > now() AND auction_start < now() AND is_sold = false AND cancelled_at IS NULL;
 
 -- name: ListActiveAndUpcomingAuctions :many
 SELECT
@@ -46,7 +91,7 @@ SELECT
     auction_end,
     is_sold
 FROM products
-WHERE auction_end > now() AND is_sold = false;
+WHERE auction_end > now() AND is_sold = false AND cancelled_at IS NULL;
 
 -- name: GetProductsByUser :many
 SELECT
@@ -78,3 +123,179 @@ FROM products
 ORDER BY created_at DESC
 LIMIT $1 OFFSET $2;
 
+
+-- name: ListProducts :many
+SELECT
+    p.id,
+    p.seller_id,
+    p.product_name,
+    p.description,
+    p.base_price,
+    p.auction_start,
+    p.auction_end,
+    p.is_sold,
+    p.cancelled_at,
+    p.category_id,
+    p.attributes,
+    p.created_at,
+    COALESCE(b.highest_bid, 0)::float AS highest_bid,
+    COALESCE(b.bid_count, 0)::bigint AS bid_count
+FROM products p
+LEFT JOIN LATERAL (
+    SELECT MAX(bid_amount) AS highest_bid, COUNT(*) AS bid_count
+    FROM bids
+    WHERE bids.product_id = p.id
+) b ON true
+WHERE
+    (sqlc.narg('status')::text IS NULL OR CASE sqlc.narg('status')::text
+        WHEN 'upcoming' THEN p.auction_start > now() AND p.is_sold = false AND p.cancelled_at IS NULL
+        WHEN 'live' THEN p.auction_start <= now() AND p.auction_end > now() AND p.is_sold = false AND p.cancelled_at IS NULL
+        WHEN 'ended' THEN p.auction_end <= now() AND p.is_sold = false AND p.cancelled_at IS NULL
+        WHEN 'sold' THEN p.is_sold = true
+        WHEN 'cancelled' THEN p.cancelled_at IS NOT NULL
+    END)
+    AND (sqlc.narg('seller_id')::uuid IS NULL OR p.seller_id = sqlc.narg('seller_id')::uuid)
+    AND (sqlc.narg('min_price')::float IS NULL OR COALESCE(b.highest_bid, p.base_price) >= sqlc.narg('min_price')::float)
+    AND (sqlc.narg('max_price')::float IS NULL OR COALESCE(b.highest_bid, p.base_price) <= sqlc.narg('max_price')::float)
+    -- A category matches its products and the products of all its descendants.
+    AND (sqlc.narg('category_id')::uuid IS NULL OR p.category_id IN (
+        WITH RECURSIVE tree AS (
+            SELECT id FROM categories WHERE categories.id = sqlc.narg('category_id')::uuid
+            UNION ALL
+            SELECT c.id FROM categories c
+            JOIN tree t ON c.parent_id = t.id
+        )
+        SELECT id FROM tree
+    ))
+    -- Exact matches, e.g. {"brand": "Fender"}.
+    AND (sqlc.narg('attributes')::jsonb IS NULL OR p.attributes @> sqlc.narg('attributes')::jsonb)
+    -- Numeric ranges, e.g. {"year": {"min": 1990, "max": 2000}}.
+    AND (sqlc.narg('attribute_ranges')::jsonb IS NULL OR NOT EXISTS (
+        SELECT 1 FROM jsonb_each(sqlc.narg('attribute_ranges')::jsonb) r
+        WHERE CASE WHEN jsonb_typeof(p.attributes -> r.key) = 'number' THEN
+            (r.value -> 'min' IS NOT NULL AND (p.attributes ->> r.key)::numeric < (r.value ->> 'min')::numeric)
+            OR (r.value -> 'max' IS NOT NULL AND (p.attributes ->> r.key)::numeric > (r.value ->> 'max')::numeric)
+        ELSE true END
+    ))
+    -- Keyset pagination: the cursor holds the sort key and id of the last row of the previous page.
+    AND (
+        sqlc.narg('cursor_time')::timestamptz IS NULL
+        OR (@sort::text = 'ending_soon' AND (p.auction_end, p.id) > (sqlc.narg('cursor_time')::timestamptz, sqlc.narg('cursor_id')::uuid))
+        OR (@sort::text <> 'ending_soon' AND (p.created_at, p.id) < (sqlc.narg('cursor_time')::timestamptz, sqlc.narg('cursor_id')::uuid))
+    )
+    -- Ending soon only has the auctions that did not end yet.
+    AND (@sort::text <> 'ending_soon' OR (p.auction_end > now() AND p.is_sold = false AND p.cancelled_at IS NULL))
+ORDER BY
+    CASE WHEN @sort::text = 'ending_soon' THEN p.auction_end END ASC,
+    CASE WHEN @sort::text = 'ending_soon' THEN p.id END ASC,
+    p.created_at DESC,
+    p.id DESC
+LIMIT @page_size;
+
+-- name: SearchProducts :many
+WITH q AS (
+    SELECT websearch_to_tsquery('english', @query::text) AS query
+), page AS (
+    SELECT
+        p.id,
+        p.seller_id,
+        p.product_name,
+        p.description,
+        p.base_price,
+        p.auction_start,
+        p.auction_end,
+        p.is_sold,
+        p.cancelled_at,
+        p.category_id,
+        p.attributes,
+        ts_rank(p.search_vector, q.query)::float AS rank,
+        COALESCE(b.highest_bid, 0)::float AS highest_bid,
+        COALESCE(b.bid_count, 0)::bigint AS bid_count
+    FROM products p
+    CROSS JOIN q
+    LEFT JOIN LATERAL (
+        SELECT MAX(bid_amount) AS highest_bid, COUNT(*) AS bid_count
+        FROM bids
+        WHERE bids.product_id = p.id
+    ) b ON true
+    WHERE
+        p.search_vector @@ q.query
+        AND (sqlc.narg('status')::text IS NULL OR CASE sqlc.narg('status')::text
+            WHEN 'upcoming' THEN p.auction_start > now() AND p.is_sold = false AND p.cancelled_at IS NULL
+            WHEN 'live' THEN p.auction_start <= now() AND p.auction_end > now() AND p.is_sold = false AND p.cancelled_at IS NULL
+            WHEN 'ended' THEN p.auction_end <= now() AND p.is_sold = false AND p.cancelled_at IS NULL
+            WHEN 'sold' THEN p.is_sold = true
+            WHEN 'cancelled' THEN p.cancelled_at IS NOT NULL
+        END)
+        AND (sqlc.narg('seller_id')::uuid IS NULL OR p.seller_id = sqlc.narg('seller_id')::uuid)
+        AND (sqlc.narg('min_price')::float IS NULL OR COALESCE(b.highest_bid, p.base_price) >= sqlc.narg('min_price')::float)
+        AND (sqlc.narg('max_price')::float IS NULL OR COALESCE(b.highest_bid, p.base_price) <= sqlc.narg('max_price')::float)
+        AND (sqlc.narg('category_id')::uuid IS NULL OR p.category_id IN (
+            WITH RECURSIVE tree AS (
+                SELECT id FROM categories WHERE categories.id = sqlc.narg('category_id')::uuid
+                UNION ALL
+                SELECT c.id FROM categories c
+                JOIN tree t ON c.parent_id = t.id
+            )
+            SELECT id FROM tree
+        ))
+        AND (sqlc.narg('attributes')::jsonb IS NULL OR p.attributes @> sqlc.narg('attributes')::jsonb)
+        AND (sqlc.narg('attribute_ranges')::jsonb IS NULL OR NOT EXISTS (
+            SELECT 1 FROM jsonb_each(sqlc.narg('attribute_ranges')::jsonb) r
+            WHERE CASE WHEN jsonb_typeof(p.attributes -> r.key) = 'number' THEN
+                (r.value -> 'min' IS NOT NULL AND (p.attributes ->> r.key)::numeric < (r.value ->> 'min')::numeric)
+                OR (r.value -> 'max' IS NOT NULL AND (p.attributes ->> r.key)::numeric > (r.value ->> 'max')::numeric)
+            ELSE true END
+        ))
+        AND (
+            sqlc.narg('cursor_rank')::float IS NULL
+            OR (ts_rank(p.search_vector, q.query)::float, p.id) < (sqlc.narg('cursor_rank')::float, sqlc.narg('cursor_id')::uuid)
+        )
+    ORDER BY rank DESC, p.id DESC
+    LIMIT @page_size
+)
+-- Highlighting is expensive, only do it for the rows of the page.
+SELECT
+    page.id,
+    page.seller_id,
+    page.product_name,
+    page.description,
+    page.base_price,
+    page.auction_start,
+    page.auction_end,
+    page.is_sold,
+    page.cancelled_at,
+    page.category_id,
+    page.attributes,
+    page.rank,
+    page.highest_bid,
+    page.bid_count,
+    ts_headline('english', page.product_name, q.query, 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>')::text AS name_highlight,
+    ts_headline('english', page.description, q.query, 'MaxFragments=2, MaxWords=20, MinWords=5, StartSel=<mark>, StopSel=</mark>')::text AS snippet
+FROM page
+CROSS JOIN q
+ORDER BY page.rank DESC, page.id DESC;
+
+-- name: ClaimEndingSoonAuctions :many
+-- Marks the live auctions ending before ends_before as notified, each one is
+-- returned only once.
+UPDATE products
+SET ending_soon_notified_at = now()
+WHERE auction_start <= now()
+    AND auction_end > now()
+    AND auction_end <= @ends_before::timestamptz
+    AND ending_soon_notified_at IS NULL
+    AND cancelled_at IS NULL
+    AND is_sold = false
+RETURNING id, product_name, auction_end;
+
+-- name: ListUnsettledAuctions :many
+SELECT id FROM products
+WHERE auction_end <= now() AND settled_at IS NULL AND cancelled_at IS NULL
+ORDER BY auction_end
+LIMIT $1;
+
+-- name: SettleProduct :execrows
+UPDATE products
+SET is_sold = $2, settled_at = now(), updated_at = now()
+WHERE id = $1 AND settled_at IS NULL;
@@ -0,0 +1,78 @@
+-- name: CreateSavedSearch :one
+INSERT INTO saved_searches (
+    user_id, name, query, category_id, min_price, max_price,
+    attributes, attribute_ranges
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, user_id, name, query, category_id, min_price, max_price,
+    attributes, attribute_ranges, created_at;
+
+-- name: CountSavedSearchesByUserId :one
+SELECT COUNT(*) FROM saved_searches
+WHERE user_id = $1;
+
+-- name: ListSavedSearchesByUserId :many
+SELECT id, user_id, name, query, category_id, min_price, max_price,
+    attributes, attribute_ranges, created_at
+FROM saved_searches
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: DeleteSavedSearch :execrows
+DELETE FROM saved_searches
+WHERE id = $1 AND user_id = $2;
+
+-- name: ClaimUnmatchedProducts :many
+-- Must run in the transaction that records the matches, so a crash leaves
+-- the products unmatched.
+UPDATE products
+SET saved_searches_matched_at = now()
+WHERE id IN (
+    SELECT id FROM products
+    WHERE saved_searches_matched_at IS NULL
+    ORDER BY created_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, product_name;
+
+-- name: RecordSavedSearchMatches :many
+-- Records the users with a saved search matching the product and returns
+-- the ones that were not told about it yet, with the name of one of their
+-- matching searches.
+WITH RECURSIVE ancestors AS (
+    SELECT c.id, c.parent_id
+    FROM categories c
+    JOIN products p ON p.category_id = c.id
+    WHERE p.id = @product_id::uuid
+    UNION ALL
+    SELECT c.id, c.parent_id
+    FROM categories c
+    JOIN ancestors a ON c.id = a.parent_id
+), matches AS (
+    SELECT DISTINCT ON (s.user_id) s.id, s.user_id
+    FROM saved_searches s
+    JOIN products p ON p.id = @product_id::uuid
+    WHERE s.user_id <> p.seller_id
+        AND p.cancelled_at IS NULL
+        AND (s.query = '' OR p.search_vector @@ websearch_to_tsquery('english', s.query))
+        AND (s.category_id IS NULL OR s.category_id IN (SELECT id FROM ancestors))
+        AND (s.min_price IS NULL OR p.base_price >= s.min_price)
+        AND (s.max_price IS NULL OR p.base_price <= s.max_price)
+        AND p.attributes @> s.attributes
+        AND NOT EXISTS (
+            SELECT 1 FROM jsonb_each(s.attribute_ranges) r
+            WHERE CASE WHEN jsonb_typeof(p.attributes -> r.key) = 'number' THEN
+                (r.value -> 'min' IS NOT NULL AND (p.attributes ->> r.key)::numeric < (r.value ->> 'min')::numeric)
+                OR (r.value -> 'max' IS NOT NULL AND (p.attributes ->> r.key)::numeric > (r.value ->> 'max')::numeric)
+            ELSE true END
+        )
+    ORDER BY s.user_id, s.created_at
+), inserted AS (
+    INSERT INTO saved_search_matches (user_id, product_id, saved_search_id)
+    SELECT m.user_id, @product_id::uuid, m.id FROM matches m
+    ON CONFLICT (user_id, product_id) DO NOTHING
+    RETURNING user_id, saved_search_id
+)
+SELECT i.user_id, s.name
+FROM inserted i
+JOIN saved_searches s ON s.id = i.saved_search_id;
@@ -0,0 +1,16 @@
+-- name: CreateSellerRating :exec
+INSERT INTO seller_ratings (product_id, seller_id, buyer_id, score, comment)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetSellerRatingSummary :one
+SELECT COUNT(*) AS count, COALESCE(AVG(score), 0)::float8 AS average
+FROM seller_ratings
+WHERE seller_id = $1;
+
+-- name: ListSellerRatings :many
+SELECT r.product_id, r.buyer_id, u.user_name AS buyer_name, r.score, r.comment, r.created_at
+FROM seller_ratings r
+JOIN users u ON u.id = r.buyer_id
+WHERE r.seller_id = $1
+ORDER BY r.created_at DESC
+LIMIT $2;
@@ -0,0 +1,9 @@
+-- name: ClaimSentEmail :execrows
+-- Affects no rows when the email of the event was already sent.
+INSERT INTO sent_emails (event_id)
+VALUES ($1)
+ON CONFLICT (event_id) DO NOTHING;
+
+-- name: ReleaseSentEmail :exec
+DELETE FROM sent_emails
+WHERE event_id = $1;
@@ -0,0 +1,53 @@
+-- name: GetUserTotp :one
+SELECT email, totp_secret, totp_enabled_at, totp_last_counter
+FROM users
+WHERE id = $1;
+
+-- name: SetUserTotpSecret :execrows
+-- Starts over an enrollment that was not confirmed, never replaces an enabled one.
+UPDATE users
+SET totp_secret = $2
+WHERE id = $1
+    AND totp_enabled_at IS NULL;
+
+-- name: EnableUserTotp :exec
+UPDATE users
+SET
+    totp_enabled_at = NOW(),
+    totp_last_counter = $2
+WHERE id = $1;
+
+-- name: UseUserTotpCounter :execrows
+-- Fails when a code of this step or a later one was already used.
+UPDATE users
+SET totp_last_counter = $2
+WHERE id = $1
+    AND totp_last_counter < $2;
+
+-- name: DisableUserTotp :exec
+UPDATE users
+SET
+    totp_secret = NULL,
+    totp_enabled_at = NULL,
+    totp_last_counter = 0
+WHERE id = $1;
+
+-- name: CreateTotpRecoveryCode :exec
+INSERT INTO totp_recovery_codes ("user_id", "code_hash")
+VALUES ($1, $2);
+
+-- name: UseTotpRecoveryCode :execrows
+UPDATE totp_recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1
+    AND code_hash = $2
+    AND used_at IS NULL;
+
+-- name: CountUnusedTotpRecoveryCodes :one
+SELECT COUNT(*) FROM totp_recovery_codes
+WHERE user_id = $1
+    AND used_at IS NULL;
+
+-- name: DeleteTotpRecoveryCodesByUserId :exec
+DELETE FROM totp_recovery_codes
+WHERE user_id = $1;
@@ -0,0 +1,19 @@
+-- name: CreateUserIdentity :exec
+INSERT INTO user_identities ("user_id", "provider", "subject", "email")
+VALUES ($1, $2, $3, $4);
+
+-- name: UseUserIdentity :one
+-- Finds the user of the identity and records the login.
+UPDATE user_identities
+SET
+    email = $3,
+    last_login_at = NOW()
+WHERE provider = $1
+    AND subject = $2
+RETURNING user_id;
+
+-- name: ListUserIdentitiesByUserId :many
+SELECT id, provider, email, created_at, last_login_at
+FROM user_identities
+WHERE user_id = $1
+ORDER BY created_at;
@@ -0,0 +1,53 @@
+-- name: TouchUserSession :exec
+-- Records the session the first time and then its last request, at most
+-- once a minute.
+INSERT INTO user_sessions ("token", "user_id", "ip", "user_agent")
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (token) DO UPDATE
+SET
+    ip = EXCLUDED.ip,
+    user_agent = EXCLUDED.user_agent,
+    last_seen_at = NOW()
+WHERE user_sessions.last_seen_at < NOW() - INTERVAL '1 minute';
+
+-- name: RenameUserSession :exec
+-- Follows the session when its token is renewed.
+UPDATE user_sessions
+SET token = @new_token
+WHERE token = @token;
+
+-- name: ListUserSessions :many
+-- Only the sessions that did not expire or were not destroyed.
+SELECT us.id, us.token, us.ip, us.user_agent, us.created_at, us.last_seen_at
+FROM user_sessions us
+JOIN sessions s ON s.token = us.token
+WHERE us.user_id = $1
+    AND s.expiry > NOW()
+ORDER BY us.last_seen_at DESC;
+
+-- name: GetUserSessionToken :one
+SELECT token
+FROM user_sessions
+WHERE id = $1
+    AND user_id = $2;
+
+-- name: ListUserSessionTokens :many
+-- Every session the user logged in, to log them out everywhere.
+SELECT token
+FROM user_sessions
+WHERE user_id = $1;
+
+-- name: DeleteUserSessionsByTokens :exec
+DELETE FROM user_sessions
+WHERE token = ANY(@tokens::text[]);
+
+-- name: DeleteStaleUserSessions :exec
+-- Purges the rows of the sessions that are gone, a day later since a new
+-- session is only saved by scs at the end of its request.
+DELETE FROM user_sessions us
+WHERE us.user_id = $1
+    AND us.last_seen_at < NOW() - INTERVAL '1 day'
+    AND NOT EXISTS (
+        SELECT 1 FROM sessions s
+        WHERE s.token = us.token AND s.expiry > NOW()
+    );
@@ -3,10 +3,15 @@ INSERT INTO users ("user_name", "email", "password_hash", "bio")
 VALUES ($1, $2, $3, $4)
 RETURNING id;
 
--- name: UpdateUser :exec
+-- name: UpdateUser :one
+-- Only the given fields change.
 UPDATE users
-SET user_name = $2, password_hash = $3, bio = $4, updated_at = NOW()
-WHERE id = $1;
+SET
+    user_name = COALESCE(sqlc.narg('user_name'), user_name),
+    bio = COALESCE(sqlc.narg('bio'), bio),
+    updated_at = NOW()
+WHERE id = @id
+RETURNING id, user_name, email, bio, created_at, updated_at, email_verified_at, role;
 
 -- name: DeleteUser :exec
 DELETE FROM users
@@ -20,7 +25,9 @@ SELECT
     email,
     bio,
     created_at,
-    updated_at
+    updated_at,
+    role,
+    email_verified_at
 FROM users
 WHERE id = $1;
 
@@ -35,4 +42,41 @@ SELECT
     created_at,
     updated_at
 FROM users
-WHERE email = $1;
+WHERE lower(email) = lower($1);
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET
+    password_hash = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkEmailVerified :exec
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, NOW())
+WHERE id = $1;
+
+-- name: IsEmailVerified :one
+SELECT email_verified_at IS NOT NULL AS verified
+FROM users
+WHERE id = $1;
+
+-- name: GetUserRole :one
+SELECT role FROM users
+WHERE id = $1;
+
+-- name: SetUserRole :execrows
+UPDATE users
+SET
+    role = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: PromoteUserToSeller :exec
+-- Only the users, the roles above can sell already.
+UPDATE users
+SET
+    role = 'seller',
+    updated_at = NOW()
+WHERE id = $1
+    AND role = 'user';
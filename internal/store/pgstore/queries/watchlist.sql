@@ -0,0 +1,44 @@
+-- name: AddToWatchlist :exec
+INSERT INTO watchlist (user_id, product_id)
+VALUES ($1, $2)
+ON CONFLICT (user_id, product_id) DO NOTHING;
+
+-- name: RemoveFromWatchlist :exec
+DELETE FROM watchlist
+WHERE user_id = $1 AND product_id = $2;
+
+-- name: IsWatchingProduct :one
+SELECT EXISTS (
+    SELECT 1 FROM watchlist
+    WHERE user_id = $1 AND product_id = $2
+)::boolean;
+
+-- name: ListWatchersByProductId :many
+SELECT user_id FROM watchlist
+WHERE product_id = $1;
+
+-- name: ListWatchlistByUserId :many
+SELECT
+    p.id,
+    p.seller_id,
+    p.product_name,
+    p.description,
+    p.base_price,
+    p.auction_start,
+    p.auction_end,
+    p.is_sold,
+    p.cancelled_at,
+    p.category_id,
+    p.attributes,
+    w.created_at AS watched_at,
+    COALESCE(b.highest_bid, 0)::float AS highest_bid,
+    COALESCE(b.bid_count, 0)::bigint AS bid_count
+FROM watchlist w
+JOIN products p ON p.id = w.product_id
+LEFT JOIN LATERAL (
+    SELECT MAX(bid_amount) AS highest_bid, COUNT(*) AS bid_count
+    FROM bids
+    WHERE bids.product_id = p.id
+) b ON true
+WHERE w.user_id = $1
+ORDER BY p.auction_end, p.id;
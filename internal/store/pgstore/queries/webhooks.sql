@@ -0,0 +1,51 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints (user_id, url, secret, events)
+VALUES ($1, $2, $3, $4)
+RETURNING id, user_id, url, secret, events, created_at;
+
+-- name: CountWebhookEndpointsByUserId :one
+SELECT COUNT(*) FROM webhook_endpoints
+WHERE user_id = $1;
+
+-- name: GetWebhookEndpointById :one
+SELECT id, user_id, url, secret, events, created_at
+FROM webhook_endpoints
+WHERE id = $1;
+
+-- name: ListWebhookEndpointsByUserId :many
+SELECT id, user_id, url, secret, events, created_at
+FROM webhook_endpoints
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: ListWebhookEndpointIdsForEvent :many
+SELECT id FROM webhook_endpoints
+WHERE user_id = @user_id AND @event_type::text = ANY(events);
+
+-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints
+WHERE id = $1 AND user_id = $2;
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (
+    endpoint_id, delivery_id, event_type, payload, status_code, error, duration_ms
+) VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, endpoint_id, delivery_id, event_type, payload, status_code, error, duration_ms, created_at;
+
+-- name: ListWebhookDeliveriesByEndpointId :many
+SELECT id, endpoint_id, delivery_id, event_type, payload, status_code, error, duration_ms, created_at
+FROM webhook_deliveries
+WHERE endpoint_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: PruneWebhookDeliveries :exec
+-- Keeps the newest @keep deliveries of the endpoint, the ones its log shows.
+DELETE FROM webhook_deliveries
+WHERE endpoint_id = @endpoint_id
+    AND id NOT IN (
+        SELECT id FROM webhook_deliveries
+        WHERE endpoint_id = @endpoint_id
+        ORDER BY created_at DESC
+        LIMIT @keep
+    );
@@ -57,7 +57,7 @@ SELECT
     created_at,
     updated_at
 FROM users
-WHERE email = $1
+WHERE lower(email) = lower($1)
 `
 
 type GetUserByEmailRow struct {
@@ -93,19 +93,23 @@ SELECT
     email,
     bio,
     created_at,
-    updated_at
+    updated_at,
+    role,
+    email_verified_at
 FROM users
 WHERE id = $1
 `
 
 type GetUserByIdRow struct {
-	ID           uuid.UUID          `json:"id"`
-	UserName     string             `json:"user_name"`
-	PasswordHash []byte             `json:"password_hash"`
-	Email        string             `json:"email"`
-	Bio          string             `json:"bio"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	ID              uuid.UUID          `json:"id"`
+	UserName        string             `json:"user_name"`
+	PasswordHash    []byte             `json:"password_hash"`
+	Email           string             `json:"email"`
+	Bio             string             `json:"bio"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	Role            string             `json:"role"`
+	EmailVerifiedAt pgtype.Timestamptz `json:"email_verified_at"`
 }
 
 func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (GetUserByIdRow, error) {
@@ -119,29 +123,142 @@ func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (GetUserByIdRow
 		&i.Bio,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
-const updateUser = `-- name: UpdateUser :exec
+const getUserRole = `-- name: GetUserRole :one
+SELECT role FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserRole(ctx context.Context, id uuid.UUID) (string, error) {
+	row := q.db.QueryRow(ctx, getUserRole, id)
+	var role string
+	err := row.Scan(&role)
+	return role, err
+}
+
+const isEmailVerified = `-- name: IsEmailVerified :one
+SELECT email_verified_at IS NOT NULL AS verified
+FROM users
+WHERE id = $1
+`
+
+func (q *Queries) IsEmailVerified(ctx context.Context, id uuid.UUID) (bool, error) {
+	row := q.db.QueryRow(ctx, isEmailVerified, id)
+	var verified bool
+	err := row.Scan(&verified)
+	return verified, err
+}
+
+const markEmailVerified = `-- name: MarkEmailVerified :exec
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, NOW())
+WHERE id = $1
+`
+
+func (q *Queries) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, markEmailVerified, id)
+	return err
+}
+
+const promoteUserToSeller = `-- name: PromoteUserToSeller :exec
 UPDATE users
-SET user_name = $2, password_hash = $3, bio = $4, updated_at = NOW()
+SET
+    role = 'seller',
+    updated_at = NOW()
 WHERE id = $1
+    AND role = 'user'
+`
+
+// Only the users, the roles above can sell already.
+func (q *Queries) PromoteUserToSeller(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, promoteUserToSeller, id)
+	return err
+}
+
+const setUserRole = `-- name: SetUserRole :execrows
+UPDATE users
+SET
+    role = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserRoleParams struct {
+	ID   uuid.UUID `json:"id"`
+	Role string    `json:"role"`
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setUserRole, arg.ID, arg.Role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE users
+SET
+    user_name = COALESCE($1, user_name),
+    bio = COALESCE($2, bio),
+    updated_at = NOW()
+WHERE id = $3
+RETURNING id, user_name, email, bio, created_at, updated_at, email_verified_at, role
 `
 
 type UpdateUserParams struct {
+	UserName pgtype.Text `json:"user_name"`
+	Bio      pgtype.Text `json:"bio"`
+	ID       uuid.UUID   `json:"id"`
+}
+
+type UpdateUserRow struct {
+	ID              uuid.UUID          `json:"id"`
+	UserName        string             `json:"user_name"`
+	Email           string             `json:"email"`
+	Bio             string             `json:"bio"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	EmailVerifiedAt pgtype.Timestamptz `json:"email_verified_at"`
+	Role            string             `json:"role"`
+}
+
+// Only the given fields change.
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (UpdateUserRow, error) {
+	row := q.db.QueryRow(ctx, updateUser, arg.UserName, arg.Bio, arg.ID)
+	var i UpdateUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserName,
+		&i.Email,
+		&i.Bio,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.Role,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET
+    password_hash = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
 	ID           uuid.UUID `json:"id"`
-	UserName     string    `json:"user_name"`
 	PasswordHash []byte    `json:"password_hash"`
-	Bio          string    `json:"bio"`
 }
 
-func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
-	_, err := q.db.Exec(ctx, updateUser,
-		arg.ID,
-		arg.UserName,
-		arg.PasswordHash,
-		arg.Bio,
-	)
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.Exec(ctx, updateUserPassword, arg.ID, arg.PasswordHash)
 	return err
 }
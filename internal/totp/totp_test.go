@@ -0,0 +1,96 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// The SHA-1 secret of the test vectors of RFC 6238, "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The SHA-1 vectors of RFC 6238 Appendix B, truncated to the last 6 of their 8 digits.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},          // 94287082
+	{1111111109, "081804"},  // 07081804
+	{1111111111, "050471"},  // 14050471
+	{1234567890, "005924"},  // 89005924
+	{2000000000, "279037"},  // 69279037
+	{20000000000, "353130"}, // 65353130
+}
+
+func TestCode(t *testing.T) {
+	for _, v := range rfcVectors {
+		got, err := Code(rfcSecret, Counter(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code at %d: %v", v.unix, err)
+		}
+		if got != v.code {
+			t.Errorf("Code at %d = %s, want %s", v.unix, got, v.code)
+		}
+	}
+
+	// The apps show the secrets in lowercase too.
+	if got, _ := Code(strings.ToLower(rfcSecret), 1); got != "287082" {
+		t.Errorf("Code of the lowercase secret = %s, want 287082", got)
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code of an invalid secret succeeded")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	counter := Counter(now)
+
+	for step := int64(-1); step <= 1; step++ {
+		code, err := Code(rfcSecret, counter+step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		matched, ok := Validate(rfcSecret, code, now, 1)
+		if !ok {
+			t.Errorf("the code of step %+d was refused", step)
+		} else if matched != counter+step {
+			t.Errorf("the code of step %+d matched the step %d, want %d", step, matched, counter+step)
+		}
+	}
+
+	for _, step := range []int64{-2, 2, -10, 10} {
+		code, err := Code(rfcSecret, counter+step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := Validate(rfcSecret, code, now, 1); ok {
+			t.Errorf("the code of step %+d was accepted", step)
+		}
+	}
+
+	// Without skew only the current step works.
+	next, _ := Code(rfcSecret, counter+1)
+	if _, ok := Validate(rfcSecret, next, now, 0); ok {
+		t.Error("the code of the next step was accepted without skew")
+	}
+
+	for _, code := range []string{"", "05047", "0050471", "abcdef"} {
+		if _, ok := Validate(rfcSecret, code, now, 1); ok {
+			t.Errorf("Validate accepted %q", code)
+		}
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Code(secret, 0); err != nil {
+		t.Errorf("the generated secret %q does not decode: %v", secret, err)
+	}
+	if other, _ := GenerateSecret(); other == secret {
+		t.Error("two secrets are the same")
+	}
+}
@@ -0,0 +1,36 @@
+package category
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+// A nil ParentID makes it a root category.
+type CreateCategoryReq struct {
+	ParentID *uuid.UUID `json:"parent_id"`
+	Name     string     `json:"name"`
+	Slug     string     `json:"slug"`
+	// Attributes the products of the category have, on top of the ones of its ancestors.
+	AttributeSchema validator.AttributeSchema `json:"attribute_schema"`
+}
+
+func (req CreateCategoryReq) Valid(ctx context.Context) validator.Evaluator {
+	var eval validator.Evaluator
+
+	eval.CheckField(validator.NotBlank(req.Name), "name", "this field cannot be blank")
+	eval.CheckField(validator.MaxChars(req.Name, 255), "name", "this field must have at most 255 characters")
+	eval.CheckField(validator.NotBlank(req.Slug), "slug", "this field cannot be blank")
+	eval.CheckField(validator.MaxChars(req.Slug, 255), "slug", "this field must have at most 255 characters")
+	eval.CheckField(
+		validator.Matches(req.Slug, validator.SlugRX),
+		"slug",
+		"must contain only lowercase letters, digits and single dashes")
+	eval.CheckField(req.ParentID == nil || *req.ParentID != uuid.Nil, "parent_id", "must be a valid uuid")
+	for key, message := range req.AttributeSchema.Valid() {
+		eval.AddFieldError(key, message)
+	}
+
+	return eval
+}
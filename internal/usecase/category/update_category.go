@@ -0,0 +1,7 @@
+package category
+
+// UpdateCategoryReq replaces every field of the category, moving it when
+// ParentID changes.
+type UpdateCategoryReq struct {
+	CreateCategoryReq
+}
@@ -0,0 +1,52 @@
+package notification
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type ListNotificationsReq struct {
+	UnreadOnly bool
+	Cursor     string
+	Limit      int32
+}
+
+// NewListNotificationsReq reads the request from the query string, values
+// that cannot be parsed are reported the same way as the Valid problems.
+func NewListNotificationsReq(query url.Values) (ListNotificationsReq, validator.Evaluator) {
+	var eval validator.Evaluator
+	req := ListNotificationsReq{
+		Cursor: query.Get("cursor"),
+		Limit:  defaultPageSize,
+	}
+
+	if raw := query.Get("unread"); raw != "" {
+		unread, err := strconv.ParseBool(raw)
+		eval.CheckField(err == nil, "unread", "must be true or false")
+		req.UnreadOnly = unread
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.ParseInt(raw, 10, 32)
+		eval.CheckField(err == nil, "limit", "must be a number")
+		req.Limit = int32(limit)
+	}
+
+	return req, eval
+}
+
+func (req ListNotificationsReq) Valid(ctx context.Context) validator.Evaluator {
+	var eval validator.Evaluator
+
+	eval.CheckField(req.Limit > 0 && req.Limit <= maxPageSize, "limit", "must be between 1 and 100")
+
+	return eval
+}
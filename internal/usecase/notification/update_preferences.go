@@ -0,0 +1,26 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+// UpdatePreferencesReq turns kinds of email on or off, the kinds left out are kept.
+type UpdatePreferencesReq struct {
+	Email map[string]bool `json:"email"`
+}
+
+func (req UpdatePreferencesReq) Valid(ctx context.Context) validator.Evaluator {
+	var eval validator.Evaluator
+
+	eval.CheckField(len(req.Email) > 0, "email", "this field cannot be empty")
+	for kind := range req.Email {
+		eval.CheckField(
+			validator.PermittedValue(kind, "outbid", "auction_won", "item_sold", "account"),
+			"email."+kind,
+			"must be one of outbid, auction_won, item_sold or account")
+	}
+
+	return eval
+}
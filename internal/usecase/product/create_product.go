@@ -4,15 +4,19 @@ import (
 	"context"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/lohanguedes/gobid/internal/validator"
 )
 
 type CreateProductReq struct {
-	ProductName  string    `json:"product_name"`
-	Description  string    `json:"description"`
-	BasePrice    float64   `json:"base_price"`
-	AuctionStart time.Time `json:"auction_start"`
-	AuctionEnd   time.Time `json:"auction_end"`
+	CategoryID  uuid.UUID `json:"category_id"`
+	ProductName string    `json:"product_name"`
+	Description string    `json:"description"`
+	// Checked against the schema of the category by ProductService.
+	Attributes   map[string]any `json:"attributes"`
+	BasePrice    float64        `json:"base_price"`
+	AuctionStart time.Time      `json:"auction_start"`
+	AuctionEnd   time.Time      `json:"auction_end"`
 }
 
 const minAuctionDuration = 2 * time.Hour
@@ -20,6 +24,7 @@ const minAuctionDuration = 2 * time.Hour
 func (req CreateProductReq) Valid(ctx context.Context) validator.Evaluator {
 	var eval validator.Evaluator
 
+	eval.CheckField(req.CategoryID != uuid.Nil, "category_id", "this field cannot be blank")
 	eval.CheckField(validator.NotBlank(req.ProductName), "product_name", "this field cannot be blank")
 	eval.CheckField(validator.NotBlank(req.Description), "description", "this field cannot be blank")
 	eval.CheckField(
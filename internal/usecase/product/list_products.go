@@ -0,0 +1,113 @@
+package product
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type ListProductsReq struct {
+	Status   string
+	SellerID *uuid.UUID
+	MinPrice *float64
+	MaxPrice *float64
+	// Includes the subcategories of the category.
+	CategoryID *uuid.UUID
+	// attr.<name>, attr.<name>.min and attr.<name>.max filters, keyed
+	// without the attr. prefix.
+	Attributes map[string]string
+	Sort       string
+	Cursor     string
+	Limit      int32
+}
+
+// NewListProductsReq reads the request from the query string, values that
+// cannot be parsed are reported the same way as the Valid problems.
+func NewListProductsReq(query url.Values) (ListProductsReq, validator.Evaluator) {
+	var eval validator.Evaluator
+	req := ListProductsReq{
+		Status: query.Get("status"),
+		Sort:   query.Get("sort"),
+		Cursor: query.Get("cursor"),
+		Limit:  defaultPageSize,
+	}
+
+	if raw := query.Get("seller_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		eval.CheckField(err == nil, "seller_id", "must be a valid uuid")
+		req.SellerID = &id
+	}
+
+	if raw := query.Get("category_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		eval.CheckField(err == nil, "category_id", "must be a valid uuid")
+		req.CategoryID = &id
+	}
+
+	for key, values := range query {
+		name, ok := strings.CutPrefix(key, "attr.")
+		if !ok || len(values) == 0 {
+			continue
+		}
+		if req.Attributes == nil {
+			req.Attributes = make(map[string]string)
+		}
+		req.Attributes[name] = values[0]
+	}
+
+	if raw := query.Get("min_price"); raw != "" {
+		price, err := strconv.ParseFloat(raw, 64)
+		eval.CheckField(err == nil, "min_price", "must be a number")
+		req.MinPrice = &price
+	}
+
+	if raw := query.Get("max_price"); raw != "" {
+		price, err := strconv.ParseFloat(raw, 64)
+		eval.CheckField(err == nil, "max_price", "must be a number")
+		req.MaxPrice = &price
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.ParseInt(raw, 10, 32)
+		eval.CheckField(err == nil, "limit", "must be a number")
+		req.Limit = int32(limit)
+	}
+
+	return req, eval
+}
+
+func (req ListProductsReq) Valid(ctx context.Context) validator.Evaluator {
+	var eval validator.Evaluator
+
+	eval.CheckField(
+		req.Status == "" || validator.PermittedValue(req.Status, "upcoming", "live", "ended", "sold", "cancelled"),
+		"status",
+		"must be one of upcoming, live, ended, sold or cancelled")
+	eval.CheckField(
+		req.Sort == "" || validator.PermittedValue(req.Sort, "newest", "ending_soon"),
+		"sort",
+		"must be one of newest or ending_soon")
+	eval.CheckField(
+		req.Sort != "ending_soon" || !validator.PermittedValue(req.Status, "ended", "sold", "cancelled"),
+		"sort",
+		"ending_soon can not be combined with the ended, sold or cancelled status")
+	eval.CheckField(req.MinPrice == nil || *req.MinPrice >= 0, "min_price", "must be bigger or equal to zero")
+	eval.CheckField(req.MaxPrice == nil || *req.MaxPrice >= 0, "max_price", "must be bigger or equal to zero")
+	eval.CheckField(
+		req.MinPrice == nil || req.MaxPrice == nil || *req.MinPrice <= *req.MaxPrice,
+		"max_price",
+		"must be bigger than min_price")
+	eval.CheckField(len(req.Attributes) == 0 || req.CategoryID != nil, "category_id", "filtering by attributes requires a category")
+	eval.CheckField(req.Limit > 0 && req.Limit <= maxPageSize, "limit", "must be between 1 and 100")
+
+	return eval
+}
@@ -0,0 +1,21 @@
+package product
+
+import (
+	"context"
+
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+type RateSellerReq struct {
+	Score   int32  `json:"score"`
+	Comment string `json:"comment"`
+}
+
+func (req RateSellerReq) Valid(ctx context.Context) validator.Evaluator {
+	var eval validator.Evaluator
+
+	eval.CheckField(req.Score >= 1 && req.Score <= 5, "score", "must be between 1 and 5")
+	eval.CheckField(validator.MaxChars(req.Comment, 1000), "comment", "must have at most 1000 chars")
+
+	return eval
+}
@@ -0,0 +1,29 @@
+package product
+
+import (
+	"context"
+	"time"
+
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+type RelistProductReq struct {
+	// Keeps the previous base price when omitted.
+	BasePrice    *float64  `json:"base_price"`
+	AuctionStart time.Time `json:"auction_start"`
+	AuctionEnd   time.Time `json:"auction_end"`
+}
+
+func (req RelistProductReq) Valid(ctx context.Context) validator.Evaluator {
+	var eval validator.Evaluator
+
+	if req.BasePrice != nil {
+		eval.CheckField(*req.BasePrice >= 0, "base_price", "base price must be or equal to zero")
+	}
+
+	eval.CheckField(req.AuctionStart.After(time.Now()), "auction_start", "auction start must be in the future")
+
+	eval.CheckField(req.AuctionEnd.Sub(req.AuctionStart) >= minAuctionDuration, "auction_end", "auction end must be at least 2 hours after auction start")
+
+	return eval
+}
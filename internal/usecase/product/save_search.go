@@ -0,0 +1,41 @@
+package product
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+// SaveSearchReq holds the same filters as the search, the user is told
+// about every new listing matching them.
+type SaveSearchReq struct {
+	Name       string     `json:"name"`
+	Query      string     `json:"q"`
+	CategoryID *uuid.UUID `json:"category_id"`
+	MinPrice   *float64   `json:"min_price"`
+	MaxPrice   *float64   `json:"max_price"`
+	// Same keys as the attr.<key> query string filters, without the prefix.
+	Attributes map[string]string `json:"attributes"`
+}
+
+func (req SaveSearchReq) Valid(ctx context.Context) validator.Evaluator {
+	var eval validator.Evaluator
+
+	eval.CheckField(validator.NotBlank(req.Name), "name", "this field cannot be blank")
+	eval.CheckField(validator.MaxChars(req.Name, 255), "name", "this field must have at most 255 characters")
+	eval.CheckField(validator.MaxChars(req.Query, 200), "q", "this field must have at most 200 characters")
+	eval.CheckField(req.MinPrice == nil || *req.MinPrice >= 0, "min_price", "must be bigger or equal to zero")
+	eval.CheckField(req.MaxPrice == nil || *req.MaxPrice >= 0, "max_price", "must be bigger or equal to zero")
+	eval.CheckField(
+		req.MinPrice == nil || req.MaxPrice == nil || *req.MinPrice <= *req.MaxPrice,
+		"max_price",
+		"must be bigger than min_price")
+	eval.CheckField(len(req.Attributes) == 0 || req.CategoryID != nil, "category_id", "filtering by attributes requires a category")
+	eval.CheckField(
+		validator.NotBlank(req.Query) || req.CategoryID != nil || req.MinPrice != nil || req.MaxPrice != nil,
+		"q",
+		"a saved search needs a query, a category or a price range")
+
+	return eval
+}
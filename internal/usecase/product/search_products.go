@@ -0,0 +1,31 @@
+package product
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+// SearchProductsReq accepts the same filters as the listing, results are always sorted by relevance.
+type SearchProductsReq struct {
+	Query string
+	ListProductsReq
+}
+
+func NewSearchProductsReq(query url.Values) (SearchProductsReq, validator.Evaluator) {
+	list, eval := NewListProductsReq(query)
+	return SearchProductsReq{
+		Query:           query.Get("q"),
+		ListProductsReq: list,
+	}, eval
+}
+
+func (req SearchProductsReq) Valid(ctx context.Context) validator.Evaluator {
+	eval := req.ListProductsReq.Valid(ctx)
+
+	eval.CheckField(validator.NotBlank(req.Query), "q", "this field cannot be blank")
+	eval.CheckField(validator.MaxChars(req.Query, 200), "q", "this field must have at most 200 characters")
+
+	return eval
+}
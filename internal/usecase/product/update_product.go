@@ -0,0 +1,45 @@
+package product
+
+import (
+	"context"
+	"time"
+
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+// UpdateProductReq is a partial update, only the fields present in the body are changed.
+type UpdateProductReq struct {
+	ProductName  *string    `json:"product_name"`
+	Description  *string    `json:"description"`
+	BasePrice    *float64   `json:"base_price"`
+	AuctionStart *time.Time `json:"auction_start"`
+	AuctionEnd   *time.Time `json:"auction_end"`
+	// Replaces all the attributes when present.
+	Attributes map[string]any `json:"attributes"`
+}
+
+func (req UpdateProductReq) Valid(ctx context.Context) validator.Evaluator {
+	var eval validator.Evaluator
+
+	if req.ProductName != nil {
+		eval.CheckField(validator.NotBlank(*req.ProductName), "product_name", "this field cannot be blank")
+	}
+	if req.Description != nil {
+		eval.CheckField(
+			validator.MinChars(*req.Description, 10) &&
+				validator.MaxChars(*req.Description, 255),
+			"description",
+			"this field must have a length between 10 and 255")
+	}
+	if req.BasePrice != nil {
+		eval.CheckField(*req.BasePrice >= 0, "base_price", "base price must be or equal to zero")
+	}
+	if req.AuctionStart != nil {
+		eval.CheckField(req.AuctionStart.After(time.Now()), "auction_start", "auction start must be in the future")
+	}
+	if req.AuctionStart != nil && req.AuctionEnd != nil {
+		eval.CheckField(req.AuctionEnd.Sub(*req.AuctionStart) >= minAuctionDuration, "auction_end", "auction end must be at least 2 hours after auction start")
+	}
+
+	return eval
+}
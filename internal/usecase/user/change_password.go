@@ -0,0 +1,23 @@
+package user
+
+import (
+	"context"
+
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+type ChangePasswordReq struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+func (req ChangePasswordReq) Valid(ctx context.Context) validator.Evaluator {
+	var eval validator.Evaluator
+
+	eval.CheckField(validator.NotBlank(req.CurrentPassword), "current_password", "this field cannot be blank")
+	eval.CheckField(validator.MinChars(req.NewPassword, 8), "new_password", "password should be bigger than 8 chars")
+	// bcrypt only hashes the first 72 bytes.
+	eval.CheckField(len(req.NewPassword) <= 72, "new_password", "password should have at most 72 bytes")
+
+	return eval
+}
@@ -0,0 +1,39 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+type CreateAPITokenReq struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// The token never expires when omitted.
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (req CreateAPITokenReq) Valid(ctx context.Context) validator.Evaluator {
+	var eval validator.Evaluator
+
+	eval.CheckField(validator.NotBlank(req.Name), "name", "this field cannot be blank")
+	eval.CheckField(validator.MaxChars(req.Name, 100), "name", "must have at most 100 chars")
+
+	eval.CheckField(len(req.Scopes) > 0, "scopes", "this field cannot be empty")
+	seen := make(map[string]bool, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		eval.CheckField(
+			validator.PermittedValue(scope, "bids:write", "products:write"),
+			"scopes",
+			"must be some of bids:write or products:write")
+		eval.CheckField(!seen[scope], "scopes", "must not repeat a scope")
+		seen[scope] = true
+	}
+
+	if req.ExpiresAt != nil {
+		eval.CheckField(req.ExpiresAt.After(time.Now()), "expires_at", "must be in the future")
+	}
+
+	return eval
+}
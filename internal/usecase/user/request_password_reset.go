@@ -0,0 +1,19 @@
+package user
+
+import (
+	"context"
+
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+type RequestPasswordResetReq struct {
+	Email string `json:"email"`
+}
+
+func (req RequestPasswordResetReq) Valid(ctx context.Context) validator.Evaluator {
+	var eval validator.Evaluator
+
+	eval.CheckField(validator.Matches(req.Email, validator.EmailRX), "email", "not a valid email")
+
+	return eval
+}
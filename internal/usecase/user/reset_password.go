@@ -0,0 +1,24 @@
+package user
+
+import (
+	"context"
+
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+// ResetPasswordReq sets a new password with the token of a reset email.
+type ResetPasswordReq struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (req ResetPasswordReq) Valid(ctx context.Context) validator.Evaluator {
+	var eval validator.Evaluator
+
+	eval.CheckField(validator.NotBlank(req.Token), "token", "this field cannot be blank")
+	eval.CheckField(validator.MinChars(req.Password, 8), "password", "password should be bigger than 8 chars")
+	// bcrypt only hashes the first 72 bytes.
+	eval.CheckField(len(req.Password) <= 72, "password", "password should have at most 72 bytes")
+
+	return eval
+}
@@ -0,0 +1,22 @@
+package user
+
+import (
+	"context"
+
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+type SetRoleReq struct {
+	Role string `json:"role"`
+}
+
+func (req SetRoleReq) Valid(ctx context.Context) validator.Evaluator {
+	var eval validator.Evaluator
+
+	eval.CheckField(
+		validator.PermittedValue(req.Role, "user", "seller", "moderator", "admin"),
+		"role",
+		"must be one of user, seller, moderator or admin")
+
+	return eval
+}
@@ -0,0 +1,22 @@
+package user
+
+import (
+	"context"
+
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+// TwoFactorCodeReq has a code of the authenticator app or, where the
+// enrollment is done, one of the recovery codes.
+type TwoFactorCodeReq struct {
+	Code string `json:"code"`
+}
+
+func (req TwoFactorCodeReq) Valid(ctx context.Context) validator.Evaluator {
+	var eval validator.Evaluator
+
+	eval.CheckField(validator.NotBlank(req.Code), "code", "this field cannot be blank")
+	eval.CheckField(validator.MaxChars(req.Code, 32), "code", "this field must have less than 32 chars")
+
+	return eval
+}
@@ -0,0 +1,32 @@
+package user
+
+import (
+	"context"
+
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+// UpdateUserReq changes the profile of the user, the fields left out are kept.
+type UpdateUserReq struct {
+	UserName *string `json:"user_name"`
+	Bio      *string `json:"bio"`
+}
+
+func (req UpdateUserReq) Valid(ctx context.Context) validator.Evaluator {
+	var eval validator.Evaluator
+
+	eval.CheckField(req.UserName != nil || req.Bio != nil, "user_name", "at least one of user_name or bio must be given")
+	if req.UserName != nil {
+		eval.CheckField(validator.NotBlank(*req.UserName), "user_name", "this field cannot be blank")
+		eval.CheckField(validator.MaxChars(*req.UserName, 255), "user_name", "this field must have less than 255 chars")
+	}
+	if req.Bio != nil {
+		eval.CheckField(
+			validator.MinChars(*req.Bio, 10) &&
+				validator.MaxChars(*req.Bio, 255),
+			"bio",
+			"this field must have a length > 10 and be < 255")
+	}
+
+	return eval
+}
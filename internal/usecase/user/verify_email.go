@@ -0,0 +1,20 @@
+package user
+
+import (
+	"context"
+
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+// VerifyEmailReq confirms the email with the token of a verification email.
+type VerifyEmailReq struct {
+	Token string `json:"token"`
+}
+
+func (req VerifyEmailReq) Valid(ctx context.Context) validator.Evaluator {
+	var eval validator.Evaluator
+
+	eval.CheckField(validator.NotBlank(req.Token), "token", "this field cannot be blank")
+
+	return eval
+}
@@ -0,0 +1,37 @@
+package webhook
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/lohanguedes/gobid/internal/validator"
+)
+
+type CreateWebhookReq struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+func (req CreateWebhookReq) Valid(ctx context.Context) validator.Evaluator {
+	var eval validator.Evaluator
+
+	u, err := url.Parse(req.URL)
+	eval.CheckField(
+		err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "",
+		"url",
+		"must be an absolute http or https url")
+	eval.CheckField(validator.MaxChars(req.URL, 2048), "url", "must have at most 2048 chars")
+
+	eval.CheckField(len(req.Events) > 0, "events", "this field cannot be empty")
+	seen := make(map[string]bool, len(req.Events))
+	for _, event := range req.Events {
+		eval.CheckField(
+			validator.PermittedValue(event, "bid.placed", "auction.ended", "item.sold"),
+			"events",
+			"must be some of bid.placed, auction.ended or item.sold")
+		eval.CheckField(!seen[event], "events", "must not repeat an event")
+		seen[event] = true
+	}
+
+	return eval
+}
@@ -0,0 +1,214 @@
+package validator
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Attribute types a category may define.
+const (
+	AttributeString  = "string"
+	AttributeNumber  = "number"
+	AttributeInteger = "integer"
+	AttributeBoolean = "boolean"
+	AttributeEnum    = "enum"
+)
+
+const maxAttributeChars = 255
+
+var AttributeNameRX = regexp.MustCompile("^[a-z][a-z0-9_]{0,63}$")
+
+// AttributeDef describes one structured field of the products of a category,
+// such as the brand or the condition of a guitar.
+type AttributeDef struct {
+	Name     string `json:"name"`
+	Label    string `json:"label"`
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+	// The accepted values of an enum attribute.
+	Options []string `json:"options,omitempty"`
+	// Bounds of number and integer attributes.
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+type AttributeSchema []AttributeDef
+
+// Lookup returns the definition of the attribute called name.
+func (s AttributeSchema) Lookup(name string) (AttributeDef, bool) {
+	for _, def := range s {
+		if def.Name == name {
+			return def, true
+		}
+	}
+	return AttributeDef{}, false
+}
+
+// Merge returns s with the definitions of child added, a child definition
+// replaces the definition of s with the same name.
+func (s AttributeSchema) Merge(child AttributeSchema) AttributeSchema {
+	merged := make(AttributeSchema, 0, len(s)+len(child))
+	for _, def := range s {
+		if _, overridden := child.Lookup(def.Name); !overridden {
+			merged = append(merged, def)
+		}
+	}
+	return append(merged, child...)
+}
+
+// Valid checks the schema itself, problems are keyed by the attribute position.
+func (s AttributeSchema) Valid() Evaluator {
+	var eval Evaluator
+
+	seen := make(map[string]bool, len(s))
+	for i, def := range s {
+		key := fmt.Sprintf("attribute_schema[%d]", i)
+
+		eval.CheckField(Matches(def.Name, AttributeNameRX), key+".name", "must start with a lowercase letter and contain only lowercase letters, digits and underscores")
+		eval.CheckField(!seen[def.Name], key+".name", "must be unique")
+		seen[def.Name] = true
+		eval.CheckField(NotBlank(def.Label), key+".label", "this field cannot be blank")
+		eval.CheckField(
+			PermittedValue(def.Type, AttributeString, AttributeNumber, AttributeInteger, AttributeBoolean, AttributeEnum),
+			key+".type",
+			"must be one of string, number, integer, boolean or enum")
+
+		if def.Type == AttributeEnum {
+			eval.CheckField(len(def.Options) > 0, key+".options", "an enum must have at least one option")
+			for _, option := range def.Options {
+				eval.CheckField(NotBlank(option) && MaxChars(option, maxAttributeChars), key+".options", "options cannot be blank or longer than 255 characters")
+			}
+		} else {
+			eval.CheckField(len(def.Options) == 0, key+".options", "only enums have options")
+		}
+
+		numeric := def.Type == AttributeNumber || def.Type == AttributeInteger
+		eval.CheckField(numeric || (def.Min == nil && def.Max == nil), key+".min", "only numbers and integers have bounds")
+		eval.CheckField(def.Min == nil || def.Max == nil || *def.Min <= *def.Max, key+".max", "must be bigger or equal to min")
+	}
+
+	return eval
+}
+
+// ValidateValues checks the attribute values of a product, decoded from
+// JSON, against the schema. Problems are keyed as "attributes.<name>".
+func (s AttributeSchema) ValidateValues(values map[string]any) Evaluator {
+	var eval Evaluator
+
+	for name := range values {
+		_, ok := s.Lookup(name)
+		eval.CheckField(ok, "attributes."+name, "unknown attribute for this category")
+	}
+
+	for _, def := range s {
+		key := "attributes." + def.Name
+		value, ok := values[def.Name]
+		if !ok || value == nil {
+			eval.CheckField(!def.Required, key, "this field is required")
+			continue
+		}
+
+		switch def.Type {
+		case AttributeString:
+			str, ok := value.(string)
+			eval.CheckField(ok, key, "must be a string")
+			eval.CheckField(!ok || (NotBlank(str) && MaxChars(str, maxAttributeChars)), key, "this field must have a length between 1 and 255")
+		case AttributeEnum:
+			str, ok := value.(string)
+			eval.CheckField(ok && PermittedValue(str, def.Options...), key, "must be one of "+strings.Join(def.Options, ", "))
+		case AttributeBoolean:
+			_, ok := value.(bool)
+			eval.CheckField(ok, key, "must be true or false")
+		case AttributeNumber, AttributeInteger:
+			number, ok := value.(float64)
+			eval.CheckField(ok, key, "must be a number")
+			if !ok {
+				continue
+			}
+			if def.Type == AttributeInteger {
+				eval.CheckField(number == math.Trunc(number), key, "must be an integer")
+			}
+			eval.CheckField(def.Min == nil || number >= *def.Min, key, fmt.Sprintf("must be bigger or equal to %g", deref(def.Min)))
+			eval.CheckField(def.Max == nil || number <= *def.Max, key, fmt.Sprintf("must be smaller or equal to %g", deref(def.Max)))
+		}
+	}
+
+	return eval
+}
+
+func deref(f *float64) float64 {
+	if f == nil {
+		return 0
+	}
+	return *f
+}
+
+// AttributeRange bounds a numeric attribute filter, nil means unbounded.
+type AttributeRange struct {
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+// ParseFilters converts listing filters from the query string to typed
+// values: "<name>" asks for an exact match and "<name>.min" or "<name>.max"
+// bound a number or integer attribute. Problems are keyed as "attr.<key>".
+func (s AttributeSchema) ParseFilters(raw map[string]string) (map[string]any, map[string]AttributeRange, Evaluator) {
+	var eval Evaluator
+	equals := make(map[string]any)
+	ranges := make(map[string]AttributeRange)
+
+	for key, value := range raw {
+		problemKey := "attr." + key
+		name, bound, _ := strings.Cut(key, ".")
+		def, ok := s.Lookup(name)
+		if !ok {
+			eval.AddFieldError(problemKey, "unknown attribute for this category")
+			continue
+		}
+
+		if bound != "" {
+			if bound != "min" && bound != "max" {
+				eval.AddFieldError(problemKey, "must be <name>, <name>.min or <name>.max")
+				continue
+			}
+			if def.Type != AttributeNumber && def.Type != AttributeInteger {
+				eval.AddFieldError(problemKey, "only numbers and integers can be filtered by range")
+				continue
+			}
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				eval.AddFieldError(problemKey, "must be a number")
+				continue
+			}
+			r := ranges[name]
+			if bound == "min" {
+				r.Min = &number
+			} else {
+				r.Max = &number
+			}
+			ranges[name] = r
+			continue
+		}
+
+		switch def.Type {
+		case AttributeString:
+			equals[name] = value
+		case AttributeEnum:
+			eval.CheckField(PermittedValue(value, def.Options...), problemKey, "must be one of "+strings.Join(def.Options, ", "))
+			equals[name] = value
+		case AttributeBoolean:
+			b, err := strconv.ParseBool(value)
+			eval.CheckField(err == nil, problemKey, "must be true or false")
+			equals[name] = b
+		case AttributeNumber, AttributeInteger:
+			number, err := strconv.ParseFloat(value, 64)
+			eval.CheckField(err == nil, problemKey, "must be a number")
+			equals[name] = number
+		}
+	}
+
+	return equals, ranges, eval
+}